@@ -0,0 +1,133 @@
+package routek
+
+import (
+	"encoding/json"
+	"log/slog"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// redacted replaces the values of redacted headers and body fields in access logs.
+const redacted = "[REDACTED]"
+
+// maxLoggedBody bounds the request bodies included in access logs; larger ones are logged by size.
+const maxLoggedBody = 4 << 10
+
+// AccessLogOptions configures AccessLog, and a route's `log` key in the route file.
+type AccessLogOptions struct {
+	// Headers includes the request headers in each line.
+	Headers bool `yaml:"headers" json:"headers"`
+	// Body includes JSON and form bodies of up to 4KB in each line; other bodies are logged by size.
+	Body bool `yaml:"body" json:"body"`
+	// Redact lists header names and body fields, matched case-insensitively, whose values are masked.
+	// Body fields are matched at any depth of a JSON body.
+	Redact []string `yaml:"redact" json:"redact"`
+}
+
+// AccessLog returns a middleware writing one info line per request to logger once the response is
+// final, with the method, path, status, duration and request ID, and the request headers and body
+// when opts asks for them. Statuses written by inner middleware, the responder or a route timeout
+// are all captured.
+func AccessLog(logger *slog.Logger, opts AccessLogOptions) Middleware {
+	redact := make(map[string]bool, len(opts.Redact))
+	for _, name := range opts.Redact {
+		redact[strings.ToLower(name)] = true
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+
+			attrs := []any{
+				"method", string(ctx.Method()),
+				"path", string(ctx.Path()),
+				"status", responseStatus(ctx),
+				"duration", time.Since(start),
+			}
+			if id := RequestID(ctx); id != "" {
+				attrs = append(attrs, "request_id", id)
+			}
+			if opts.Headers {
+				attrs = append(attrs, slog.Group("headers", loggedHeaders(&ctx.Request.Header, redact)...))
+			}
+			if opts.Body {
+				attrs = append(attrs, "body", loggedBody(ctx, redact))
+			}
+
+			logger.Info("routek: request", attrs...)
+		}
+	}
+}
+
+// loggedHeaders returns the request headers as log attributes, masking redacted ones.
+func loggedHeaders(header *fasthttp.RequestHeader, redact map[string]bool) []any {
+	var attrs []any
+	header.VisitAll(func(key, value []byte) {
+		v := string(value)
+		if redact[strings.ToLower(string(key))] {
+			v = redacted
+		}
+		attrs = append(attrs, slog.String(string(key), v))
+	})
+
+	return attrs
+}
+
+// loggedBody returns the request body for the log with redacted fields masked. Bodies that are large,
+// or neither JSON nor a URL-encoded form, are summarised by their size so nothing unmasked leaks.
+func loggedBody(ctx *fasthttp.RequestCtx, redact map[string]bool) any {
+	body := ctx.PostBody()
+	if len(body) == 0 {
+		return ""
+	}
+
+	summary := slog.Int("bytes", len(body))
+	if len(body) > maxLoggedBody {
+		return slog.GroupValue(summary)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(string(ctx.Request.Header.ContentType()))
+	switch mediaType {
+	case mimeJSON, "":
+		var v any
+		if err := json.Unmarshal(body, &v); err == nil {
+			return redactJSON(v, redact)
+		}
+	case mimeForm:
+		fields := make(map[string]any)
+		ctx.PostArgs().VisitAll(func(key, value []byte) {
+			v := string(value)
+			if redact[strings.ToLower(string(key))] {
+				v = redacted
+			}
+			fields[string(key)] = v
+		})
+		return fields
+	}
+
+	return slog.GroupValue(summary)
+}
+
+// redactJSON masks the values of redacted object keys throughout a decoded JSON value.
+func redactJSON(v any, redact map[string]bool) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for key, value := range t {
+			if redact[strings.ToLower(key)] {
+				t[key] = redacted
+			} else {
+				t[key] = redactJSON(value, redact)
+			}
+		}
+	case []any:
+		for i, value := range t {
+			t[i] = redactJSON(value, redact)
+		}
+	}
+
+	return v
+}
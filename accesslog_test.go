@@ -0,0 +1,46 @@
+package routek
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type crashHandlers struct{}
+
+func (crashHandlers) Crash(ctx *fasthttp.RequestCtx) (any, error) {
+	panic("crash")
+}
+
+func TestAccessLogRecordsPanics(t *testing.T) {
+	var logs bytes.Buffer
+	rt := newTestRouter(t, `
+ops:
+  route:
+    - get: /crash
+      handler: Crash
+      log: true
+`, Config{
+		Handlers:      map[string]any{"ops": crashHandlers{}},
+		Logger:        slog.New(slog.NewJSONHandler(&logs, nil)),
+		RecoverPanics: true,
+	})
+
+	if status := serve(rt.Handler, fasthttp.MethodGet, "/crash").Response.StatusCode(); status != fasthttp.StatusInternalServerError {
+		t.Fatalf("status %d, want 500", status)
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", logs.String(), err)
+	}
+	if line["msg"] != "routek: request" || line["route"] != "ops.Crash" {
+		t.Errorf("log line %v, want the access log of ops.Crash", line)
+	}
+	if line["status"] != float64(fasthttp.StatusInternalServerError) {
+		t.Errorf("logged status %v, want 500", line["status"])
+	}
+}
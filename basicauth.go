@@ -0,0 +1,61 @@
+package routek
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BasicAuth returns a middleware that requires HTTP Basic credentials matching users, a map of user
+// names to passwords. Requests with missing or wrong credentials are answered with 401 and a
+// WWW-Authenticate challenge for realm through the route's responder. Passwords are compared in
+// constant time, and unknown users take as long as wrong passwords.
+func BasicAuth(users map[string]string, realm string) Middleware {
+	// Hash the passwords up front so every comparison is between equal-length digests.
+	digests := make(map[string][sha256.Size]byte, len(users))
+	for user, password := range users {
+		digests[user] = sha256.Sum256([]byte(password))
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm) + `, charset="UTF-8"`
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			user, password, ok := basicCredentials(ctx.Request.Header.Peek(fasthttp.HeaderAuthorization))
+			if ok {
+				expected, known := digests[user]
+				given := sha256.Sum256(password)
+				if subtle.ConstantTimeCompare(given[:], expected[:]) == 1 && known {
+					next(ctx)
+					return
+				}
+			}
+
+			ctx.Response.Header.Set(fasthttp.HeaderWWWAuthenticate, challenge)
+			ResponderFrom(ctx).Error(ctx, fasthttp.StatusUnauthorized, CodeUnauthorized, "unauthorized", nil)
+		}
+	}
+}
+
+// basicCredentials decodes an Authorization header of the Basic scheme.
+func basicCredentials(header []byte) (string, []byte, bool) {
+	const prefix = "basic "
+	if len(header) < len(prefix) || !bytes.EqualFold(header[:len(prefix)], []byte(prefix)) {
+		return "", nil, false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(header[len(prefix):])))
+	if err != nil {
+		return "", nil, false
+	}
+
+	user, password, ok := bytes.Cut(decoded, []byte(":"))
+	if !ok {
+		return "", nil, false
+	}
+
+	return string(user), password, true
+}
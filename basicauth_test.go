@@ -0,0 +1,66 @@
+package routek
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func basicHeader(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestBasicAuth(t *testing.T) {
+	rt := newTestRouter(t, `
+admin:
+  middleware: [basic]
+  route:
+    - get: /ping
+      handler: Ping
+`, Config{
+		Handlers:   map[string]any{"admin": probeHandlers{}},
+		Middleware: map[string]Middleware{"basic": BasicAuth(map[string]string{"ops": "s3cret"}, "admin")},
+	})
+
+	tests := []struct {
+		name          string
+		authorization string
+		status        int
+	}{
+		{"valid", basicHeader("ops", "s3cret"), fasthttp.StatusOK},
+		{"lowercase scheme", "basic " + base64.StdEncoding.EncodeToString([]byte("ops:s3cret")), fasthttp.StatusOK},
+		{"wrong password", basicHeader("ops", "guess"), fasthttp.StatusUnauthorized},
+		{"unknown user", basicHeader("root", "s3cret"), fasthttp.StatusUnauthorized},
+		{"unknown user without password", basicHeader("root", ""), fasthttp.StatusUnauthorized},
+		{"missing", "", fasthttp.StatusUnauthorized},
+		{"other scheme", "Bearer token", fasthttp.StatusUnauthorized},
+		{"malformed", "Basic not-base64!", fasthttp.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.authorization != "" {
+				headers = []string{fasthttp.HeaderAuthorization, tt.authorization}
+			}
+			ctx := serve(rt.Handler, fasthttp.MethodGet, "/ping", headers...)
+
+			if status := ctx.Response.StatusCode(); status != tt.status {
+				t.Fatalf("status %d, want %d", status, tt.status)
+			}
+			challenge := string(ctx.Response.Header.Peek(fasthttp.HeaderWWWAuthenticate))
+			if tt.status == fasthttp.StatusOK {
+				if challenge != "" {
+					t.Errorf("WWW-Authenticate = %q on success", challenge)
+				}
+				return
+			}
+			if want := `Basic realm="admin", charset="UTF-8"`; challenge != want {
+				t.Errorf("WWW-Authenticate = %q, want %q", challenge, want)
+			}
+			if code := decodeEnvelope(t, ctx.Response.Body())["code"]; code != string(CodeUnauthorized) {
+				t.Errorf("code %v, want %s through the responder", code, CodeUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package routek
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Decoder decodes the request body into v, a pointer to the handler's request struct.
+type Decoder func(ctx *fasthttp.RequestCtx, v any) error
+
+const (
+	mimeForm      = "application/x-www-form-urlencoded"
+	mimeMultipart = "multipart/form-data"
+)
+
+// defaultDecoders are the request body decoders available without Config.Decoders.
+var defaultDecoders = map[string]Decoder{
+	mimeJSON:      decodeJSON,
+	mimeForm:      decodeForm,
+	mimeMultipart: decodeMultipart,
+}
+
+// decodersFor merges Config.Decoders over the defaults, keyed by lower-case media type.
+func decodersFor(cfg Config) map[string]Decoder {
+	if len(cfg.Decoders) == 0 {
+		return defaultDecoders
+	}
+
+	decoders := make(map[string]Decoder, len(defaultDecoders)+len(cfg.Decoders))
+	for mediaType, decoder := range defaultDecoders {
+		decoders[mediaType] = decoder
+	}
+	for mediaType, decoder := range cfg.Decoders {
+		decoders[strings.ToLower(mediaType)] = decoder
+	}
+
+	return decoders
+}
+
+// errUnsupportedMediaType is returned by bind when no decoder handles the request's Content-Type.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// bind decodes the request body into v with the decoder for its Content-Type. Requests without a
+// Content-Type are decoded as JSON.
+func bind(ctx *fasthttp.RequestCtx, decoders map[string]Decoder, v any) error {
+	mediaType := mimeJSON
+	if contentType := string(ctx.Request.Header.ContentType()); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return errUnsupportedMediaType
+		}
+		mediaType = parsed
+	}
+
+	decoder, ok := decoders[mediaType]
+	if !ok || decoder == nil {
+		return errUnsupportedMediaType
+	}
+
+	return decoder(ctx, v)
+}
+
+func decodeJSON(ctx *fasthttp.RequestCtx, v any) error {
+	return json.Unmarshal(ctx.PostBody(), v)
+}
+
+func decodeForm(ctx *fasthttp.RequestCtx, v any) error {
+	values := make(map[string][]string)
+	ctx.PostArgs().VisitAll(func(key, value []byte) {
+		values[string(key)] = append(values[string(key)], string(value))
+	})
+
+	return bindFields(v, values, nil)
+}
+
+func decodeMultipart(ctx *fasthttp.RequestCtx, v any) error {
+	form, err := ctx.MultipartForm()
+	if err != nil {
+		return err
+	}
+
+	return bindFields(v, form.Value, form.File)
+}
+
+var (
+	fileHeaderType  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeadersType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+// bindFields sets the fields of the struct v points to from form values and files. A field is named by
+// its `form` tag, else its `json` tag, else its Go name; "-" skips it. Files bind to *multipart.FileHeader
+// and []*multipart.FileHeader fields, values to strings, booleans, numbers and slices of them.
+func bindFields(v any, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		name := fieldName(sf)
+		if !sf.IsExported() || name == "-" {
+			continue
+		}
+
+		field := rv.Field(i)
+		switch sf.Type {
+		case fileHeaderType:
+			if fh := files[name]; len(fh) > 0 {
+				field.Set(reflect.ValueOf(fh[0]))
+			}
+			continue
+		case fileHeadersType:
+			if fh := files[name]; len(fh) > 0 {
+				field.Set(reflect.ValueOf(fh))
+			}
+			continue
+		}
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+
+		if sf.Type.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(sf.Type, len(vals), len(vals))
+			for j, val := range vals {
+				if err := setField(slice.Index(j), val); err != nil {
+					return fmt.Errorf("field %q: %w", name, err)
+				}
+			}
+			field.Set(slice)
+			continue
+		}
+
+		if err := setField(field, vals[0]); err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// fieldName returns the form name of a struct field.
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		if tag, _, _ := strings.Cut(sf.Tag.Get(key), ","); tag != "" {
+			return tag
+		}
+	}
+
+	return sf.Name
+}
+
+// setField parses val into a field of a basic kind.
+func setField(field reflect.Value, val string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot bind form values to %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package routek
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// sizeUnits maps the suffixes accepted by parseSize to their multiplier. KB, MB and GB are binary,
+// like their KiB, MiB and GiB spellings.
+var sizeUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+	{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+	{"k", 1 << 10}, {"m", 1 << 20}, {"g", 1 << 30},
+	{"b", 1},
+}
+
+// parseSize parses a positive byte size such as 512, 64KB or 1MB.
+func parseSize(s string) (int64, error) {
+	value := strings.ToLower(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix)), unit.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)/multiplier {
+		return 0, fmt.Errorf("max_body %q must be a positive size such as 512KB or 1MB", s)
+	}
+
+	return n * multiplier, nil
+}
+
+// bodyLimitMiddleware answers 413 through the responder when the request body, as declared by
+// Content-Length or as read, exceeds limit bytes.
+func bodyLimitMiddleware(limit int64, responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if int64(ctx.Request.Header.ContentLength()) > limit || int64(len(ctx.PostBody())) > limit {
+				responder.Error(ctx, fasthttp.StatusRequestEntityTooLarge, CodePayloadTooLarge, "request body too large", nil)
+				return
+			}
+
+			next(ctx)
+		}
+	}
+}
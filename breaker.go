@@ -0,0 +1,132 @@
+package routek
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// BreakerOptions configures CircuitBreaker, and a route's `breaker` key in the route file.
+type BreakerOptions struct {
+	// Threshold is the number of consecutive failed requests that opens the breaker.
+	Threshold int
+	// Cooldown is how long the open breaker answers 503 before letting a trial request through.
+	Cooldown time.Duration
+}
+
+// breaker states: closed passes every request, open rejects them until the cooldown ends, and
+// half-open lets a single trial request decide whether to close or reopen.
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type breaker struct {
+	opts     BreakerOptions
+	mu       sync.Mutex
+	state    int
+	failures int
+	openedAt time.Time
+}
+
+// CircuitBreaker returns a middleware that answers 503 with a Retry-After header through the responder
+// once opts.Threshold consecutive requests have failed, until opts.Cooldown has elapsed. A request fails
+// when its handler returned an error, timed out or panicked. After the cooldown one trial request is let
+// through: success closes the breaker, failure opens it for another cooldown. Each call returns an
+// independent breaker.
+func CircuitBreaker(opts BreakerOptions) Middleware {
+	b := &breaker{opts: opts}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if wait, ok := b.allow(time.Now()); !ok {
+				seconds := int(math.Ceil(wait.Seconds()))
+				ctx.Response.Header.Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				ResponderFrom(ctx).Error(ctx, fasthttp.StatusServiceUnavailable, CodeServiceUnavailable, "service unavailable", nil)
+				return
+			}
+
+			// Record from a defer so a panicking handler counts as a failure and cannot leave a trial pending.
+			ok := false
+			defer func() { b.record(time.Now(), ok) }()
+
+			next(ctx)
+			// Checked first: after a timeout the abandoned handler may still be setting the error.
+			ok = ctx.LastTimeoutErrorResponse() == nil && handlerError(ctx) == nil
+		}
+	}
+}
+
+// allow reports whether a request may pass at now and, when it may not, how long until the next trial.
+func (b *breaker) allow(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if wait := b.openedAt.Add(b.opts.Cooldown).Sub(now); wait > 0 {
+			return wait, false
+		}
+		b.state = breakerHalfOpen
+		return 0, true
+	case breakerHalfOpen:
+		// The trial request is still running.
+		return b.opts.Cooldown, false
+	default:
+		return 0, true
+	}
+}
+
+// record updates the breaker with the outcome of a request that was let through.
+func (b *breaker) record(now time.Time, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ok {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.opts.Threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// breakerOptions converts a route's breaker value, a map with a threshold and a cooldown duration.
+func breakerOptions(val any) (*BreakerOptions, error) {
+	v, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New("route breaker must be a map such as {threshold: 5, cooldown: 30s}")
+	}
+
+	opts := &BreakerOptions{}
+	for key, option := range v {
+		switch key {
+		case "threshold":
+			n, ok := wholeNumber(option)
+			if !ok || n <= 0 {
+				return nil, errors.New("route breaker threshold must be a positive number")
+			}
+			opts.Threshold = n
+		case "cooldown":
+			s, _ := option.(string)
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return nil, errors.New("route breaker cooldown must be a positive duration such as 30s")
+			}
+			opts.Cooldown = d
+		default:
+			return nil, fmt.Errorf("route breaker has unknown option %q", key)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,128 @@
+package routek
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestBreakerStates(t *testing.T) {
+	b := &breaker{opts: BreakerOptions{Threshold: 2, Cooldown: 10 * time.Second}}
+	start := time.Now()
+
+	// Closed: failures below the threshold, and a success in between, keep it closed.
+	b.record(start, false)
+	b.record(start, true)
+	b.record(start, false)
+	if _, ok := b.allow(start); !ok {
+		t.Fatal("breaker opened before threshold consecutive failures")
+	}
+
+	// The second consecutive failure opens it for the cooldown.
+	b.record(start, false)
+	wait, ok := b.allow(start.Add(4 * time.Second))
+	if ok {
+		t.Fatal("open breaker let a request through")
+	}
+	if wait != 6*time.Second {
+		t.Errorf("wait %v, want the 6s left of the cooldown", wait)
+	}
+
+	// Half-open: one trial passes, and others wait while it runs.
+	trial := start.Add(10 * time.Second)
+	if _, ok := b.allow(trial); !ok {
+		t.Fatal("breaker did not let a trial through after the cooldown")
+	}
+	if _, ok := b.allow(trial); ok {
+		t.Fatal("half-open breaker let a second request through")
+	}
+
+	// A failed trial reopens it for another full cooldown.
+	b.record(trial, false)
+	if _, ok := b.allow(trial.Add(9 * time.Second)); ok {
+		t.Fatal("breaker did not reopen after a failed trial")
+	}
+
+	// A successful trial closes it.
+	trial = trial.Add(10 * time.Second)
+	if _, ok := b.allow(trial); !ok {
+		t.Fatal("breaker did not let a second trial through")
+	}
+	b.record(trial, true)
+	for i := 0; i < 3; i++ {
+		if _, ok := b.allow(trial); !ok {
+			t.Fatal("breaker is not closed after a successful trial")
+		}
+	}
+}
+
+type flakyHandlers struct {
+	calls *int
+	err   *error
+}
+
+func (h flakyHandlers) Quotes(ctx *fasthttp.RequestCtx) (any, error) {
+	*h.calls++
+	return "quotes", *h.err
+}
+
+func TestBreakerRoute(t *testing.T) {
+	var (
+		calls int
+		err   = errors.New("upstream unavailable")
+	)
+	rt := newTestRouter(t, `
+quotes:
+  route:
+    - get: /quotes
+      handler: Quotes
+      breaker: {threshold: 2, cooldown: 1m}
+`, Config{Handlers: map[string]any{"quotes": flakyHandlers{calls: &calls, err: &err}}})
+
+	for i := 0; i < 2; i++ {
+		if status := serve(rt.Handler, fasthttp.MethodGet, "/quotes").Response.StatusCode(); status != fasthttp.StatusInternalServerError {
+			t.Fatalf("request %d: status %d, want the handler's 500", i+1, status)
+		}
+	}
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/quotes")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusServiceUnavailable {
+		t.Fatalf("status %d after the threshold, want 503", status)
+	}
+	if got := string(ctx.Response.Header.Peek("Retry-After")); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	if code := decodeEnvelope(t, ctx.Response.Body())["code"]; code != string(CodeServiceUnavailable) {
+		t.Errorf("code %v, want %s through the responder", code, CodeServiceUnavailable)
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want the open breaker to skip it", calls)
+	}
+}
+
+func TestBreakerOptions(t *testing.T) {
+	// JSON route files decode numbers as float64.
+	opts, err := breakerOptions(map[string]any{"threshold": float64(5), "cooldown": "30s"})
+	if err != nil {
+		t.Fatalf("breakerOptions: %v", err)
+	}
+	if opts.Threshold != 5 || opts.Cooldown != 30*time.Second {
+		t.Errorf("options %+v, want threshold 5 and cooldown 30s", opts)
+	}
+
+	for name, val := range map[string]any{
+		"not a map":          "5/30s",
+		"fractional":         map[string]any{"threshold": 2.5, "cooldown": "30s"},
+		"zero threshold":     map[string]any{"threshold": 0, "cooldown": "30s"},
+		"bad cooldown":       map[string]any{"threshold": 5, "cooldown": "soon"},
+		"unknown option":     map[string]any{"threshold": 5, "cooldown": "30s", "window": "1m"},
+		"negative cooldown":  map[string]any{"threshold": 5, "cooldown": "-1s"},
+		"threshold a string": map[string]any{"threshold": "5", "cooldown": "30s"},
+	} {
+		if _, err := breakerOptions(val); err == nil {
+			t.Errorf("%s: breakerOptions(%v) succeeded, want an error", name, val)
+		}
+	}
+}
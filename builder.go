@@ -0,0 +1,65 @@
+package routek
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+
+	"github.com/fasthttp/router"
+)
+
+// RouterBuilder assembles the groups of a router from several sources, such as plugins loaded at
+// startup, without a route file. Its methods are safe for concurrent use.
+type RouterBuilder struct {
+	cfg Config
+
+	mu       sync.Mutex
+	doc      Document
+	handlers map[string]any
+	err      error
+}
+
+// NewRouterBuilder returns a builder for a router configured by cfg. Groups of cfg.Document and
+// targets of cfg.Handlers are kept, and groups added to the builder join them.
+func NewRouterBuilder(cfg Config) *RouterBuilder {
+	doc := make(Document, len(cfg.Document))
+	maps.Copy(doc, cfg.Document)
+	handlers := make(map[string]any, len(cfg.Handlers))
+	maps.Copy(handlers, cfg.Handlers)
+
+	return &RouterBuilder{cfg: cfg, doc: doc, handlers: handlers}
+}
+
+// AddGroup adds a group served by target, with routes whose paths are used as given. Adding a group
+// name twice makes Build fail.
+func (b *RouterBuilder) AddGroup(name string, target any, routes []Route) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, dup := b.doc[name]; dup {
+		if b.err == nil {
+			b.err = fmt.Errorf("routek: group %q added twice", name)
+		}
+		return
+	}
+
+	b.doc[name] = Group{Routes: append([]Route(nil), routes...)}
+	b.handlers[name] = target
+}
+
+// Build binds and registers every group added so far like NewRouter, with the same handler, path and
+// duplicate route checks. The builder can keep being used afterwards.
+func (b *RouterBuilder) Build() (*router.Router, error) {
+	b.mu.Lock()
+	cfg := b.cfg
+	cfg.Document = maps.Clone(b.doc)
+	cfg.Handlers = maps.Clone(b.handlers)
+	err := b.err
+	b.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRouter(cfg)
+}
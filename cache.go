@@ -0,0 +1,156 @@
+package routek
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultCacheSize is the number of responses kept by the LRU cache used when Config.ResponseCache is nil.
+const DefaultCacheSize = 1024
+
+// CachedResponse is a response stored by a ResponseCache.
+type CachedResponse struct {
+	ContentType     string
+	ContentEncoding string
+	Body            []byte
+	// ETag is the response's ETag header, as set by a responder created WithETag; empty when it had none.
+	ETag string
+	// Vary is the response's Vary header, such as the Origin added by CORS; empty when it had none.
+	Vary string
+	// Expires is when the response stops being served; expired entries are treated as missing.
+	Expires time.Time
+}
+
+// ResponseCache stores the responses of routes declaring `cache`. Implementations must be safe for
+// concurrent use and decide eviction themselves; expiry is checked by routek.
+type ResponseCache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+}
+
+// CacheRecorder observes whether each request to a cached route was a hit or a miss. Route is the
+// declared path pattern, as for MetricsRecorder.
+type CacheRecorder interface {
+	ObserveCache(method, route string, hit bool)
+}
+
+// NewLRUCache returns a ResponseCache holding up to size responses, evicting the least recently used.
+func NewLRUCache(size int) ResponseCache {
+	return &lruCache{size: max(size, 1), items: make(map[string]*list.Element), order: list.New()}
+}
+
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+func (c *lruCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*lruEntry).resp, true
+}
+
+func (c *lruCache) Set(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, resp: resp})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// cacheMiddleware serves 200 responses from store for ttl. The key covers the method, the request URI
+// with its query, and the Accept and Accept-Encoding headers, which can change the body. Responses that
+// set cookies, stream their body or timed out are not stored, nor are those of a responder created
+// WithRequestID once the request has an ID, since a hit would replay another request's request_id. A
+// stored ETag and Vary header are replayed, and a hit whose If-None-Match matches the ETag is answered
+// 304 Not Modified.
+func cacheMiddleware(store ResponseCache, ttl time.Duration, recorder CacheRecorder, route string, responder Responder) Middleware {
+	stamper, ok := responder.(requestIDStamper)
+	stampsID := ok && stamper.stampsRequestID()
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			key := string(ctx.Method()) + " " + string(ctx.RequestURI()) +
+				"\n" + string(ctx.Request.Header.Peek("Accept")) +
+				"\n" + string(ctx.Request.Header.Peek("Accept-Encoding"))
+
+			if cached, ok := store.Get(key); ok && time.Now().Before(cached.Expires) {
+				if recorder != nil {
+					recorder.ObserveCache(string(ctx.Method()), route, true)
+				}
+				if cached.Vary != "" {
+					ctx.Response.Header.Set(fasthttp.HeaderVary, cached.Vary)
+				}
+				if cached.ETag != "" {
+					ctx.Response.Header.Set(fasthttp.HeaderETag, cached.ETag)
+					if (ctx.IsGet() || ctx.IsHead()) && etagMatches(string(ctx.Request.Header.Peek(fasthttp.HeaderIfNoneMatch)), cached.ETag) {
+						ctx.Response.ResetBody()
+						ctx.SetStatusCode(fasthttp.StatusNotModified)
+						return
+					}
+				}
+				ctx.SetStatusCode(fasthttp.StatusOK)
+				ctx.SetContentType(cached.ContentType)
+				if cached.ContentEncoding != "" {
+					ctx.Response.Header.Set(fasthttp.HeaderContentEncoding, cached.ContentEncoding)
+				}
+				ctx.SetBody(cached.Body)
+				return
+			}
+
+			if recorder != nil {
+				recorder.ObserveCache(string(ctx.Method()), route, false)
+			}
+			next(ctx)
+
+			// A timed-out handler may still be writing ctx.Response.
+			if ctx.LastTimeoutErrorResponse() != nil {
+				return
+			}
+			resp := &ctx.Response
+			if resp.StatusCode() != fasthttp.StatusOK || resp.IsBodyStream() || (stampsID && RequestID(ctx) != "") {
+				return
+			}
+			setsCookie := false
+			resp.Header.VisitAllCookie(func(_, _ []byte) { setsCookie = true })
+			if setsCookie {
+				return
+			}
+			store.Set(key, &CachedResponse{
+				ContentType:     string(resp.Header.ContentType()),
+				ContentEncoding: string(resp.Header.ContentEncoding()),
+				ETag:            string(resp.Header.Peek(fasthttp.HeaderETag)),
+				Vary:            string(resp.Header.Peek(fasthttp.HeaderVary)),
+				Body:            append([]byte(nil), resp.Body()...),
+				Expires:         time.Now().Add(ttl),
+			})
+		}
+	}
+}
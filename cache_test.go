@@ -0,0 +1,61 @@
+package routek
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type countryHandlers struct {
+	calls *int
+}
+
+func (h countryHandlers) Countries(ctx *fasthttp.RequestCtx) (any, error) {
+	*h.calls++
+	ctx.Response.Header.Set(fasthttp.HeaderVary, "Accept-Language")
+	return []string{"ID", "NL"}, nil
+}
+
+const cachedRoutes = `
+geo:
+  route:
+    - get: /countries
+      handler: Countries
+      cache: 1m
+`
+
+func TestCacheReplaysVary(t *testing.T) {
+	var calls int
+	rt := newTestRouter(t, cachedRoutes, Config{Handlers: map[string]any{"geo": countryHandlers{calls: &calls}}})
+
+	serve(rt.Handler, fasthttp.MethodGet, "/countries")
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/countries")
+	if calls != 1 {
+		t.Fatalf("handler called %d times, want the second request served from the cache", calls)
+	}
+	if got := string(ctx.Response.Header.Peek(fasthttp.HeaderVary)); got != "Accept-Language" {
+		t.Errorf("hit Vary = %q, want the stored Accept-Language", got)
+	}
+}
+
+func TestCacheWithRequestIDs(t *testing.T) {
+	var calls int
+	rt := newTestRouter(t, cachedRoutes, Config{
+		Handlers:         map[string]any{"geo": countryHandlers{calls: &calls}},
+		Responder:        NewResponder(false, WithRequestID()),
+		GlobalMiddleware: []Middleware{RequestIDMiddleware()},
+	})
+
+	for _, id := range []string{"req-1", "req-2"} {
+		ctx := serve(rt.Handler, fasthttp.MethodGet, "/countries", RequestIDHeader, id)
+		if got := decodeEnvelope(t, ctx.Response.Body())["request_id"]; got != id {
+			t.Errorf("request %s: envelope request_id %v, want its own", id, got)
+		}
+		if got := string(ctx.Response.Header.Peek(RequestIDHeader)); got != id {
+			t.Errorf("request %s: %s = %q", id, RequestIDHeader, got)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler called %d times, want each stamped response rendered afresh", calls)
+	}
+}
@@ -0,0 +1,85 @@
+package routek
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+// handlerNames returns the handler names a route binds: its canary handlers in name order, or its handler.
+func (r Route) handlerNames() []string {
+	if len(r.Canary) == 0 {
+		return []string{r.Handler}
+	}
+
+	names := make([]string, 0, len(r.Canary))
+	for name := range r.Canary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handlerLabel names the route's handler in errors, logs and RegisteredRoute: the handler, or the
+// canary handlers joined with commas.
+func (r Route) handlerLabel() string {
+	return strings.Join(r.handlerNames(), ",")
+}
+
+// canaryWeights converts a route's canary value, a map of handler names to positive weights.
+func canaryWeights(val any) (map[string]int, error) {
+	v, ok := val.(map[string]any)
+	if !ok || len(v) == 0 {
+		return nil, errors.New("route canary must be a map of handler names to weights such as {V1: 90, V2: 10}")
+	}
+
+	weights := make(map[string]int, len(v))
+	for name, weight := range v {
+		n, ok := wholeNumber(weight)
+		if !ok || n <= 0 {
+			return nil, fmt.Errorf("route canary weight of %q must be a positive number", name)
+		}
+		weights[name] = n
+	}
+	return weights, nil
+}
+
+// canaryPicker returns a function choosing an int in [0, n): from source when set, which is locked
+// since sources are not safe for concurrent use, and from the global generator otherwise.
+func canaryPicker(source rand.Source) func(n int) int {
+	if source == nil {
+		return rand.IntN
+	}
+
+	var mu sync.Mutex
+	rng := rand.New(source)
+	return func(n int) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.IntN(n)
+	}
+}
+
+// canaryHandler dispatches each request to one of handlers, chosen at random in proportion to weights.
+func canaryHandler(handlers []fasthttp.RequestHandler, weights []int, pick func(n int) int) fasthttp.RequestHandler {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		n := pick(total)
+		for i, w := range weights {
+			if n < w {
+				handlers[i](ctx)
+				return
+			}
+			n -= w
+		}
+	}
+}
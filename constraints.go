@@ -0,0 +1,149 @@
+package routek
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// paramConstraint validates a single path parameter value.
+type paramConstraint struct {
+	name  string
+	match func(string) bool
+}
+
+// compileConstraint parses a constraint declaration: "int", "uuid" or "regex:<pattern>".
+func compileConstraint(name, kind string) (paramConstraint, error) {
+	switch {
+	case kind == "int":
+		return paramConstraint{name: name, match: func(v string) bool {
+			_, err := strconv.ParseInt(v, 10, 64)
+			return err == nil
+		}}, nil
+	case kind == "uuid":
+		return paramConstraint{name: name, match: uuidPattern.MatchString}, nil
+	case strings.HasPrefix(kind, "regex:"):
+		re, err := regexp.Compile("^(?:" + strings.TrimPrefix(kind, "regex:") + ")$")
+		if err != nil {
+			return paramConstraint{}, fmt.Errorf("constraint for %q: %w", name, err)
+		}
+		return paramConstraint{name: name, match: re.MatchString}, nil
+	default:
+		return paramConstraint{}, fmt.Errorf("constraint for %q: unknown type %q (want int, uuid or regex:<pattern>)", name, kind)
+	}
+}
+
+// constraintMiddleware compiles the declared constraints for path into a middleware that answers
+// 404 through the responder when a parameter value does not satisfy its constraint.
+func constraintMiddleware(path string, constraints map[string]string, responder Responder) (Middleware, error) {
+	params := pathParams(path)
+
+	checks := make([]paramConstraint, 0, len(constraints))
+	for name, kind := range constraints {
+		if !params[name] {
+			return nil, fmt.Errorf("constraint for %q does not match a parameter in %s", name, path)
+		}
+
+		check, err := compileConstraint(name, kind)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for _, check := range checks {
+				// Optional parameters that were not supplied are left to the handler.
+				value, ok := ctx.UserValue(check.name).(string)
+				if ok && !check.match(value) {
+					responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "Not Found", nil)
+					return
+				}
+			}
+
+			next(ctx)
+		}
+	}, nil
+}
+
+// queryParam is a required query parameter, with an optional constraint on its value.
+type queryParam struct {
+	name  string
+	check *paramConstraint
+}
+
+// parseQueryParams compiles a route's query declarations: a name, optionally followed by ":" and a
+// constraint type, such as "page" or "limit:int".
+func parseQueryParams(declared []string) ([]queryParam, error) {
+	params := make([]queryParam, 0, len(declared))
+	for _, decl := range declared {
+		name, kind, typed := strings.Cut(decl, ":")
+		if name == "" {
+			return nil, fmt.Errorf("query parameter %q must have a name", decl)
+		}
+
+		param := queryParam{name: name}
+		if typed {
+			check, err := compileConstraint(name, kind)
+			if err != nil {
+				return nil, fmt.Errorf("query %w", err)
+			}
+			param.check = &check
+		}
+		params = append(params, param)
+	}
+
+	return params, nil
+}
+
+// queryMiddleware answers 400 through the responder when a required query parameter is missing or
+// does not satisfy its constraint.
+func queryMiddleware(params []queryParam, responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			args := ctx.QueryArgs()
+			for _, param := range params {
+				if !args.Has(param.name) {
+					responder.Error(ctx, fasthttp.StatusBadRequest, CodeBadRequest, "missing query parameter "+param.name, nil)
+					return
+				}
+				if param.check != nil && !param.check.match(string(args.Peek(param.name))) {
+					responder.Error(ctx, fasthttp.StatusBadRequest, CodeBadRequest, "invalid query parameter "+param.name, nil)
+					return
+				}
+			}
+
+			next(ctx)
+		}
+	}
+}
+
+// pathParams returns the names of the {param} segments declared in path.
+func pathParams(path string) map[string]bool {
+	params := make(map[string]bool)
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			return params
+		}
+
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			return params
+		}
+
+		name := path[start+1 : start+end]
+		if i := strings.IndexAny(name, ":?"); i >= 0 {
+			name = name[:i]
+		}
+		params[name] = true
+
+		path = path[start+end+1:]
+	}
+}
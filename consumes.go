@@ -0,0 +1,93 @@
+package routek
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// parseMediaRanges validates the media types declared under key, such as application/json,
+// application/* or */*, and returns them in lower case.
+func parseMediaRanges(key string, types []string) ([]string, error) {
+	ranges := make([]string, 0, len(types))
+	for _, declared := range types {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(declared))
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if err != nil || len(params) > 0 || !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			return nil, fmt.Errorf("%s %q must be a media type such as application/json or application/*", key, declared)
+		}
+		ranges = append(ranges, mediaType)
+	}
+
+	return ranges, nil
+}
+
+// parseProduces validates a route's produces list, which unlike consumes must name concrete media types.
+func parseProduces(types []string) ([]string, error) {
+	mediaTypes, err := parseMediaRanges("produces", types)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, mediaType := range mediaTypes {
+		if strings.Contains(mediaType, "*") {
+			return nil, fmt.Errorf("produces %q must be a concrete media type, not a wildcard", types[i])
+		}
+	}
+
+	return mediaTypes, nil
+}
+
+// matchMediaRange reports whether mediaType falls within mediaRange, honouring type/* and */* wildcards.
+func matchMediaRange(mediaRange, mediaType string) bool {
+	switch {
+	case mediaRange == "*/*":
+		return true
+	case strings.HasSuffix(mediaRange, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	default:
+		return mediaRange == mediaType
+	}
+}
+
+// consumesMiddleware answers 415 through the responder when the request's Content-Type matches none of
+// ranges. Requests without a body and without a Content-Type are let through.
+func consumesMiddleware(ranges []string, responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			contentType := string(ctx.Request.Header.ContentType())
+			if contentType == "" && len(ctx.PostBody()) == 0 {
+				next(ctx)
+				return
+			}
+
+			if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+				for _, mediaRange := range ranges {
+					if matchMediaRange(mediaRange, mediaType) {
+						next(ctx)
+						return
+					}
+				}
+			}
+
+			responder.Error(ctx, fasthttp.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "unsupported media type", nil)
+		}
+	}
+}
+
+// producesMiddleware answers 406 through the responder when the request's Accept header accepts none of
+// mediaTypes. Requests without an Accept header are let through.
+func producesMiddleware(mediaTypes []string, responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if negotiate(string(ctx.Request.Header.Peek("Accept")), mediaTypes...) == "" {
+				responder.Error(ctx, fasthttp.StatusNotAcceptable, CodeNotAcceptable, "not acceptable", nil)
+				return
+			}
+
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package routek
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// CORSOptions configures the CORS middleware.
+type CORSOptions struct {
+	// AllowedOrigins lists the origins allowed to make cross-origin requests. "*" allows any origin,
+	// and an entry such as "https://*.example.com" allows any subdomain.
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, HEAD, POST, PUT, PATCH and DELETE.
+	AllowedMethods []string
+	// AllowedHeaders lists the request headers a preflight may ask for. When empty, the headers
+	// requested by the preflight are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders lists the response headers made readable to the browser.
+	ExposedHeaders []string
+	// AllowCredentials lets browsers send cookies and authorization headers. The matching origin is
+	// then echoed back instead of "*", as browsers require.
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache a preflight result; zero leaves it to the browser.
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{
+	fasthttp.MethodGet, fasthttp.MethodHead, fasthttp.MethodPost,
+	fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete,
+}
+
+// CORS returns a middleware that applies opts to cross-origin requests. Preflight requests are
+// answered with 204 without reaching the handler; other requests get the CORS headers and continue.
+// Requests from origins that are not allowed pass through without CORS headers, so the browser
+// blocks them.
+//
+// Registered through Config.GlobalMiddleware it also answers preflights for every route; put it
+// first so middleware such as authentication does not reject them.
+func CORS(opts CORSOptions) Middleware {
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(opts.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(opts.ExposedHeaders, ", ")
+
+	var maxAge string
+	if opts.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(opts.MaxAge / time.Second))
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			origin := string(ctx.Request.Header.Peek("Origin"))
+			if origin == "" {
+				next(ctx)
+				return
+			}
+
+			h := &ctx.Response.Header
+			h.Add("Vary", "Origin")
+
+			preflight := ctx.IsOptions() && len(ctx.Request.Header.Peek("Access-Control-Request-Method")) > 0
+			allowed, wildcard := matchOrigin(opts.AllowedOrigins, origin)
+			if !allowed {
+				if preflight {
+					ctx.SetStatusCode(fasthttp.StatusNoContent)
+					return
+				}
+				next(ctx)
+				return
+			}
+
+			if wildcard && !opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if opts.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if exposeHeaders != "" {
+					h.Set("Access-Control-Expose-Headers", exposeHeaders)
+				}
+				next(ctx)
+				return
+			}
+
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", allowMethods)
+			if allowHeaders != "" {
+				h.Set("Access-Control-Allow-Headers", allowHeaders)
+			} else if requested := ctx.Request.Header.Peek("Access-Control-Request-Headers"); len(requested) > 0 {
+				h.SetBytesV("Access-Control-Allow-Headers", requested)
+			}
+			if maxAge != "" {
+				h.Set("Access-Control-Max-Age", maxAge)
+			}
+			ctx.SetStatusCode(fasthttp.StatusNoContent)
+		}
+	}
+}
+
+// matchOrigin reports whether origin is allowed, and whether it was allowed by the "*" entry.
+func matchOrigin(allowed []string, origin string) (ok, wildcard bool) {
+	for _, pattern := range allowed {
+		switch {
+		case pattern == "*":
+			return true, true
+		case strings.EqualFold(pattern, origin):
+			return true, false
+		case strings.Contains(pattern, "*"):
+			prefix, suffix, _ := strings.Cut(pattern, "*")
+			if len(origin) > len(prefix)+len(suffix) &&
+				strings.HasPrefix(strings.ToLower(origin), strings.ToLower(prefix)) &&
+				strings.HasSuffix(strings.ToLower(origin), strings.ToLower(suffix)) {
+				return true, false
+			}
+		}
+	}
+
+	return false, false
+}
@@ -0,0 +1,122 @@
+package routek
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// corsRouter serves the probe routes with CORS registered as global middleware.
+func corsRouter(t *testing.T, opts CORSOptions) fasthttp.RequestHandler {
+	t.Helper()
+
+	rt := newTestRouter(t, `
+api:
+  route:
+    - get: /ping
+      handler: Ping
+`, Config{
+		Handlers:         map[string]any{"api": probeHandlers{}},
+		GlobalMiddleware: []Middleware{CORS(opts)},
+	})
+
+	return rt.Handler
+}
+
+func TestCORSPreflight(t *testing.T) {
+	handler := corsRouter(t, CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{fasthttp.MethodGet, fasthttp.MethodPost},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         10 * time.Minute,
+	})
+
+	ctx := serve(handler, fasthttp.MethodOptions, "/ping",
+		"Origin", "https://app.example.com",
+		"Access-Control-Request-Method", fasthttp.MethodPost,
+		"Access-Control-Request-Headers", "Content-Type")
+
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusNoContent {
+		t.Errorf("status %d, want 204", status)
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type, Authorization",
+		"Access-Control-Max-Age":           "600",
+		"Access-Control-Allow-Credentials": "",
+	}
+	for name, value := range want {
+		if got := string(ctx.Response.Header.Peek(name)); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if len(ctx.Response.Body()) != 0 {
+		t.Errorf("preflight reached the handler, body %q", ctx.Response.Body())
+	}
+}
+
+func TestCORSPreflightFromDisallowedOrigin(t *testing.T) {
+	handler := corsRouter(t, CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})
+
+	ctx := serve(handler, fasthttp.MethodOptions, "/ping",
+		"Origin", "https://evil.example.net",
+		"Access-Control-Request-Method", fasthttp.MethodGet)
+
+	if got := ctx.Response.Header.Peek("Access-Control-Allow-Origin"); got != nil {
+		t.Errorf("Access-Control-Allow-Origin = %q, want none", got)
+	}
+}
+
+func TestCORSCredentialedRequest(t *testing.T) {
+	handler := corsRouter(t, CORSOptions{
+		AllowedOrigins:   []string{"*"},
+		AllowCredentials: true,
+		ExposedHeaders:   []string{"X-Request-ID"},
+	})
+
+	ctx := serve(handler, fasthttp.MethodGet, "/ping", "Origin", "https://app.example.com", "Cookie", "session=1")
+
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Fatalf("status %d, want the handler's 200", status)
+	}
+	want := map[string]string{
+		// Browsers reject "*" on credentialed requests, so the origin is echoed.
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Expose-Headers":    "X-Request-ID",
+		"Vary":                             "Origin",
+	}
+	for name, value := range want {
+		if got := string(ctx.Response.Header.Peek(name)); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestCORSWildcardWithoutCredentials(t *testing.T) {
+	handler := corsRouter(t, CORSOptions{AllowedOrigins: []string{"*"}})
+
+	ctx := serve(handler, fasthttp.MethodGet, "/ping", "Origin", "https://app.example.com")
+	if got := string(ctx.Response.Header.Peek("Access-Control-Allow-Origin")); got != "*" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+	}
+}
+
+func TestMatchOrigin(t *testing.T) {
+	tests := []struct {
+		pattern, origin string
+		ok              bool
+	}{
+		{"https://app.example.com", "https://APP.example.com", true},
+		{"https://*.example.com", "https://api.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://api.example.com.evil.net", false},
+	}
+	for _, tt := range tests {
+		if ok, _ := matchOrigin([]string{tt.pattern}, tt.origin); ok != tt.ok {
+			t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.pattern, tt.origin, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package routek
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// parseSunset parses a sunset date given as 2006-01-02 or RFC 3339.
+func parseSunset(s string) (time.Time, error) {
+	for _, layout := range []string{time.DateOnly, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("route sunset %q must be a date such as 2026-06-30", s)
+}
+
+// deprecationMiddleware adds the Deprecation header and, when sunset is set, the Sunset header
+// (RFC 8594) to every response of a route.
+func deprecationMiddleware(deprecated bool, sunset time.Time) Middleware {
+	var sunsetHeader string
+	if !sunset.IsZero() {
+		sunsetHeader = string(fasthttp.AppendHTTPDate(nil, sunset))
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			if deprecated {
+				ctx.Response.Header.Set("Deprecation", "true")
+			}
+			if sunsetHeader != "" {
+				ctx.Response.Header.Set("Sunset", sunsetHeader)
+			}
+
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package routek
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// WithETag adds a weak ETag to 200 success responses to GET and HEAD requests, and answers 304 Not
+// Modified without a body when the request's If-None-Match matches it. The tag covers the message, code
+// and data in the negotiated format, not the timestamp or request ID, so it only changes with the
+// content. Computing it costs a hash of the data on every such response.
+func WithETag() ResponderOption {
+	return func(o *responderOptions) {
+		o.etag = true
+	}
+}
+
+// notModified sets the ETag of a success envelope when WithETag is enabled and reports whether the
+// request's If-None-Match matched it, in which case the 304 has been written.
+func (o responderOptions) notModified(ctx *fasthttp.RequestCtx, status int, mediaType string, resp Response[any]) bool {
+	if !o.etag || status != fasthttp.StatusOK || !(ctx.IsGet() || ctx.IsHead()) {
+		return false
+	}
+
+	content, err := json.Marshal(struct {
+		Message string `json:"message"`
+		Code    Code   `json:"code"`
+		Data    any    `json:"data"`
+	}{resp.Message, resp.Code, resp.Data})
+	if err != nil {
+		return false
+	}
+
+	sum := sha256.Sum256(append([]byte(mediaType+"\n"), content...))
+	tag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	ctx.Response.Header.Set(fasthttp.HeaderETag, tag)
+
+	if !etagMatches(string(ctx.Request.Header.Peek(fasthttp.HeaderIfNoneMatch)), tag) {
+		return false
+	}
+
+	ctx.Response.ResetBody()
+	ctx.SetStatusCode(fasthttp.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists tag, comparing weakly as RFC 9110 requires.
+func etagMatches(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package routek
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestETag(t *testing.T) {
+	rt := newTestRouter(t, `
+api:
+  route:
+    - get: /ping
+      handler: Ping
+    - delete: /ping
+      handler: Delete
+`, Config{Handlers: map[string]any{"api": probeHandlers{}}, Responder: NewResponder(false, WithETag())})
+
+	first := serve(rt.Handler, fasthttp.MethodGet, "/ping")
+	tag := string(first.Response.Header.Peek(fasthttp.HeaderETag))
+	if !strings.HasPrefix(tag, `W/"`) {
+		t.Fatalf("ETag = %q, want a weak tag", tag)
+	}
+	if again := string(serve(rt.Handler, fasthttp.MethodGet, "/ping").Response.Header.Peek(fasthttp.HeaderETag)); again != tag {
+		t.Errorf("ETag changed from %q to %q for the same content", tag, again)
+	}
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/ping", fasthttp.HeaderIfNoneMatch, `"other", `+tag)
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusNotModified {
+		t.Errorf("matching If-None-Match: status %d, want 304", status)
+	}
+	if len(ctx.Response.Body()) != 0 {
+		t.Errorf("304 body %q, want none", ctx.Response.Body())
+	}
+	if got := string(ctx.Response.Header.Peek(fasthttp.HeaderETag)); got != tag {
+		t.Errorf("304 ETag = %q, want %q", got, tag)
+	}
+
+	ctx = serve(rt.Handler, fasthttp.MethodGet, "/ping", fasthttp.HeaderIfNoneMatch, `W/"stale"`)
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Errorf("stale If-None-Match: status %d, want 200", status)
+	}
+	if data := decodeEnvelope(t, ctx.Response.Body())["data"]; data != "pong" {
+		t.Errorf("stale If-None-Match: data %v, want the full response", data)
+	}
+
+	if got := serve(rt.Handler, fasthttp.MethodDelete, "/ping").Response.Header.Peek(fasthttp.HeaderETag); got != nil {
+		t.Errorf("DELETE ETag = %q, want none", got)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		ifNoneMatch string
+		ok          bool
+	}{
+		{`W/"abc"`, true},
+		{`"abc"`, true},
+		{`"x", W/"abc"`, true},
+		{`*`, true},
+		{`"abcd"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if ok := etagMatches(tt.ifNoneMatch, `W/"abc"`); ok != tt.ok {
+			t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, ok, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,664 @@
+package routek
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-konsultin/errk"
+	"github.com/valyala/fasthttp"
+)
+
+// binding carries what the generated handler closures need to turn results into responses.
+type binding struct {
+	responder Responder
+	mapError  func(error) (int, Code, string)
+	logger    *slog.Logger
+	// decoders decode request structs, keyed by media type.
+	decoders map[string]Decoder
+	// defaultCode replaces CodeInternalError for errors that are not errk errors; empty keeps it.
+	defaultCode Code
+	// successCode replaces the code of success envelopes; empty picks OK or CREATED by status.
+	successCode Code
+	// noContentOnNil answers nil data with a 200 or default status as 204 No Content.
+	noContentOnNil bool
+	// validator, if set, checks decoded request structs before the handler runs.
+	validator Validator
+}
+
+func buildHandler(target any, methodName string, b *binding) (fasthttp.RequestHandler, error) {
+	if methodName == "" {
+		return nil, errors.New("handler name is empty")
+	}
+
+	target, methodName, err := resolveTarget(target, methodName)
+	if err != nil {
+		return nil, err
+	}
+
+	value := reflect.ValueOf(target)
+	method := value.MethodByName(methodName)
+	if !method.IsValid() {
+		// Method lookup takes precedence; only fall back to targets that are handlers themselves.
+		if handler, ok := directHandler(target, methodName); ok {
+			return handler, nil
+		}
+
+		// Methods with pointer receivers are not in a struct value's method set.
+		if t := value.Type(); t.Kind() != reflect.Pointer {
+			if _, ok := reflect.PointerTo(t).MethodByName(methodName); ok {
+				return nil, fmt.Errorf("handler %q has a pointer receiver but the target is a %T value; register a *%s instead", methodName, target, t)
+			}
+		}
+
+		return nil, fmt.Errorf("handler %q not found on %T", methodName, target)
+	}
+
+	// Streaming handlers write the response themselves, event by event, so the responder is never called.
+	if fn, ok := method.Interface().(func(*fasthttp.RequestCtx, chan<- Event)); ok {
+		return b.streamHandler(fn), nil
+	}
+
+	methodType := method.Type()
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+
+	// bodyType is the request struct decoded from the body for func(*fasthttp.RequestCtx, *T) handlers,
+	// with the decoder for the request's Content-Type.
+	withContext, bodyType, ok := requestParams(methodType)
+	if !ok {
+		return nil, fmt.Errorf("handler %q must accept a *fasthttp.RequestCtx, optionally preceded by a context.Context and followed by a pointer to a request struct, or be a func(*fasthttp.RequestCtx, chan<- routek.Event) stream", methodName)
+	}
+	if checker, ok := b.validator.(typeChecker); ok && bodyType != nil {
+		if err := checker.checkType(bodyType); err != nil {
+			return nil, fmt.Errorf("handler %q request struct: %w", methodName, err)
+		}
+	}
+
+	// Common shapes are asserted to their concrete func type once, so requests skip reflect.Value.Call
+	// and the argument/result slices it allocates on every invocation.
+	switch fn := method.Interface().(type) {
+	case func(*fasthttp.RequestCtx):
+		return fn, nil
+	case func(*fasthttp.RequestCtx) error:
+		return func(ctx *fasthttp.RequestCtx) {
+			if err := fn(ctx); err != nil {
+				b.respondError(ctx, err)
+				return
+			}
+			b.respondDone(ctx)
+		}, nil
+	case func(*fasthttp.RequestCtx) (any, error):
+		return func(ctx *fasthttp.RequestCtx) {
+			data, err := fn(ctx)
+			if err != nil {
+				b.respondError(ctx, err)
+				return
+			}
+
+			b.respondSuccess(ctx, fasthttp.StatusOK, data)
+		}, nil
+	case func(*fasthttp.RequestCtx) (int, any, error):
+		return func(ctx *fasthttp.RequestCtx) {
+			status, data, err := fn(ctx)
+			if err != nil {
+				b.respondError(ctx, err)
+				return
+			}
+
+			b.respondSuccess(ctx, status, data)
+		}, nil
+	case func(context.Context, *fasthttp.RequestCtx) (any, error):
+		return func(ctx *fasthttp.RequestCtx) {
+			data, err := fn(Context(ctx), ctx)
+			if err != nil {
+				b.respondError(ctx, err)
+				return
+			}
+
+			b.respondSuccess(ctx, fasthttp.StatusOK, data)
+		}, nil
+	}
+
+	// args builds the reflected call arguments, reporting false when the request was already answered.
+	args := func(ctx *fasthttp.RequestCtx) ([]reflect.Value, bool) {
+		in := make([]reflect.Value, 0, methodType.NumIn())
+		if withContext {
+			in = append(in, reflect.ValueOf(Context(ctx)))
+		}
+		in = append(in, reflect.ValueOf(ctx))
+		if bodyType != nil {
+			body := reflect.New(bodyType)
+			if err := bind(ctx, b.decoders, body.Interface()); err != nil {
+				if errors.Is(err, errUnsupportedMediaType) {
+					b.responder.Error(ctx, fasthttp.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "unsupported media type", err)
+				} else {
+					b.responder.Error(ctx, fasthttp.StatusBadRequest, CodeBadRequest, "invalid request body", err)
+				}
+				return nil, false
+			}
+			if b.validator != nil {
+				if err := b.validator.Validate(body.Interface()); err != nil {
+					var fields FieldErrors
+					if errors.As(err, &fields) {
+						respondValidation(b.responder, ctx, fields)
+					} else {
+						b.respondError(ctx, err)
+					}
+					return nil, false
+				}
+			}
+			in = append(in, body)
+		}
+
+		return in, true
+	}
+
+	// raw is set for handlers whose data result is declared as []byte or io.Reader; they bypass the
+	// envelope. Data of other declared types is only raw when it implements RawResponse.
+	var raw bool
+	if n := methodType.NumOut(); n >= 2 {
+		out := methodType.Out(n - 2)
+		raw = out == bytesType || out == readerType
+	}
+
+	switch methodType.NumOut() {
+	case 0:
+		return func(ctx *fasthttp.RequestCtx) {
+			if in, ok := args(ctx); ok {
+				method.Call(in)
+			}
+		}, nil
+	case 1:
+		if methodType.Out(0) != errType {
+			return nil, fmt.Errorf("handler %q must return either nothing or error", methodName)
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			in, ok := args(ctx)
+			if !ok {
+				return
+			}
+
+			if res := method.Call(in); !res[0].IsNil() {
+				b.respondError(ctx, res[0].Interface().(error))
+				return
+			}
+			b.respondDone(ctx)
+		}, nil
+	case 2:
+		if methodType.Out(1) != errType {
+			return nil, fmt.Errorf("handler %q must return (any, error)", methodName)
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			in, ok := args(ctx)
+			if !ok {
+				return
+			}
+
+			res := method.Call(in)
+			data := res[0].Interface()
+			if !res[1].IsNil() {
+				b.respondError(ctx, res[1].Interface().(error))
+				return
+			}
+
+			if raw {
+				b.respondRaw(ctx, fasthttp.StatusOK, data)
+				return
+			}
+			b.respondSuccess(ctx, fasthttp.StatusOK, data)
+		}, nil
+	case 3:
+		if methodType.Out(0).Kind() != reflect.Int || methodType.Out(2) != errType {
+			return nil, fmt.Errorf("handler %q must return (int, any, error)", methodName)
+		}
+
+		return func(ctx *fasthttp.RequestCtx) {
+			in, ok := args(ctx)
+			if !ok {
+				return
+			}
+
+			res := method.Call(in)
+			if !res[2].IsNil() {
+				b.respondError(ctx, res[2].Interface().(error))
+				return
+			}
+
+			if raw {
+				b.respondRaw(ctx, int(res[0].Int()), res[1].Interface())
+				return
+			}
+			b.respondSuccess(ctx, int(res[0].Int()), res[1].Interface())
+		}, nil
+	default:
+		return nil, fmt.Errorf("handler %q must return nothing, error, (any, error) or (int, any, error)", methodName)
+	}
+}
+
+// hasHandler reports whether name resolves on target, either as a method or through directHandler.
+func hasHandler(target any, name string) bool {
+	if target == nil {
+		return false
+	}
+
+	target, name, err := resolveTarget(target, name)
+	if err != nil {
+		return false
+	}
+	if reflect.ValueOf(target).MethodByName(name).IsValid() {
+		return true
+	}
+
+	_, ok := directHandler(target, name)
+	return ok
+}
+
+// resolveTarget follows a dotted handler name such as "Admin.Delete" through exported struct fields of
+// target, returning the field holding the method and the method name. Addressable fields are returned as
+// pointers so pointer-receiver methods resolve. Names without a dot, and handler maps, are returned as-is.
+func resolveTarget(target any, name string) (any, string, error) {
+	segments := strings.Split(name, ".")
+	if _, ok := target.(map[string]fasthttp.RequestHandler); ok || len(segments) == 1 {
+		return target, name, nil
+	}
+
+	// parent names the value holding segment i, for error messages.
+	parent := func(i int) string {
+		if i == 0 {
+			return "the handler target"
+		}
+		return strings.Join(segments[:i], ".")
+	}
+
+	value := reflect.ValueOf(target)
+	for i, field := range segments[:len(segments)-1] {
+		for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, "", fmt.Errorf("handler %q: %s is nil", name, parent(i))
+			}
+			value = value.Elem()
+		}
+
+		if value.Kind() != reflect.Struct {
+			return nil, "", fmt.Errorf("handler %q: %s is a %s, not a struct", name, parent(i), value.Type())
+		}
+
+		sf, ok := value.Type().FieldByName(field)
+		if !ok || !sf.IsExported() {
+			return nil, "", fmt.Errorf("handler %q: no exported field %q on %s", name, field, value.Type())
+		}
+
+		next, err := value.FieldByIndexErr(sf.Index)
+		if err != nil {
+			return nil, "", fmt.Errorf("handler %q: %s: %w", name, parent(i+1), err)
+		}
+		if next.Kind() != reflect.Pointer && next.Kind() != reflect.Interface && next.CanAddr() {
+			next = next.Addr()
+		}
+		value = next
+	}
+
+	last := len(segments) - 1
+	if (value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface) && value.IsNil() {
+		return nil, "", fmt.Errorf("handler %q: %s is nil", name, parent(last))
+	}
+
+	return value.Interface(), segments[last], nil
+}
+
+// directHandler resolves name against a target that is a fasthttp.RequestHandler, which serves every
+// route in the group, or a map[string]fasthttp.RequestHandler keyed by handler name.
+func directHandler(target any, name string) (fasthttp.RequestHandler, bool) {
+	switch t := target.(type) {
+	case fasthttp.RequestHandler:
+		return t, t != nil
+	case func(*fasthttp.RequestCtx):
+		return t, t != nil
+	case map[string]fasthttp.RequestHandler:
+		handler := t[name]
+		return handler, handler != nil
+	}
+
+	return nil, false
+}
+
+var (
+	ctxType     = reflect.TypeOf(&fasthttp.RequestCtx{})
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+)
+
+// requestParams checks that a method's parameters are an optional context.Context, a
+// *fasthttp.RequestCtx and an optional pointer to a request struct, in that order. body is the
+// request struct type, or nil.
+func requestParams(t reflect.Type) (withContext bool, body reflect.Type, ok bool) {
+	i := 0
+	if t.NumIn() > 0 && t.In(0) == contextType {
+		withContext, i = true, 1
+	}
+	if t.NumIn() <= i || t.In(i) != ctxType {
+		return false, nil, false
+	}
+
+	switch t.NumIn() - i {
+	case 1:
+		return withContext, nil, true
+	case 2:
+		if isStructPointer(t.In(i + 1)) {
+			return withContext, t.In(i + 1).Elem(), true
+		}
+	}
+
+	return false, nil, false
+}
+
+// handlerMethods returns the names of target's exported methods that accept a request, in
+// alphabetical order.
+func handlerMethods(target any) []string {
+	t := reflect.TypeOf(target)
+	if t == nil {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumMethod(); i++ {
+		if _, _, ok := requestParams(reflect.ValueOf(target).Method(i).Type()); ok {
+			names = append(names, t.Method(i).Name)
+		}
+	}
+
+	return names
+}
+
+// isStructPointer reports whether t is a pointer to a struct type.
+func isStructPointer(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct
+}
+
+// Created wraps data returned by a handler so it is sent through the responder's Created: a 201 with a
+// Location header set to location. It takes precedence over a status returned alongside it.
+func Created(location string, data any) any {
+	return created{location: location, data: data}
+}
+
+type created struct {
+	location string
+	data     any
+}
+
+// ResponseEnvelope is implemented by handler results that choose their own success envelope.
+// A zero status or an empty message falls back to the handler's status and "success".
+type ResponseEnvelope interface {
+	Envelope() (status int, message string, data any)
+}
+
+// ResponseCoder is implemented by handler results that choose the code of their success envelope.
+// An empty code falls back to the route's success_code, then to OK or CREATED.
+type ResponseCoder interface {
+	ResponseCode() Code
+}
+
+// Result is a ResponseEnvelope carrying a status, message, code and data, e.g.
+// return routek.Result{Message: "user updated", Data: user}, nil.
+type Result struct {
+	Status  int
+	Message string
+	Code    Code
+	Data    any
+}
+
+// Envelope implements ResponseEnvelope.
+func (r Result) Envelope() (int, string, any) {
+	return r.Status, r.Message, r.Data
+}
+
+// ResponseCode implements ResponseCoder.
+func (r Result) ResponseCode() Code {
+	return r.Code
+}
+
+// respondDone finishes an error-only handler that returned nil. A body the handler wrote is kept. Without
+// one, responders created WithSuccessOnNilError write a success envelope with the handler's status, and
+// others answer a response still at 200 with 204 No Content.
+func (b *binding) respondDone(ctx *fasthttp.RequestCtx) {
+	resp := &ctx.Response
+	if len(resp.Body()) > 0 || resp.IsBodyStream() {
+		return
+	}
+
+	if r, ok := b.responder.(nilErrorSuccess); ok && r.successOnNilError() {
+		b.respondSuccess(ctx, resp.StatusCode(), nil)
+		return
+	}
+	if resp.StatusCode() == fasthttp.StatusOK {
+		respondNoContent(b.responder, ctx)
+	}
+}
+
+// respondSuccess writes data with a handler-chosen status; zero means 200.
+func (b *binding) respondSuccess(ctx *fasthttp.RequestCtx, status int, data any) {
+	// A typed nil pointer is treated as no data, so every responder writes the same null data.
+	data = nilData(data)
+	if data == nil && b.noContentOnNil && (status == 0 || status == fasthttp.StatusOK) {
+		respondNoContent(b.responder, ctx)
+		return
+	}
+
+	message := "success"
+	code := b.successCode
+	if coder, ok := data.(ResponseCoder); ok && coder.ResponseCode() != "" {
+		code = coder.ResponseCode()
+	}
+
+	switch v := data.(type) {
+	case created:
+		respondCreated(b.responder, ctx, v.location, v.data)
+		return
+	case RawResponse:
+		b.respondRaw(ctx, status, v)
+		return
+	case ResponseEnvelope:
+		envStatus, envMessage, envData := v.Envelope()
+		if envStatus != 0 {
+			status = envStatus
+		}
+		if envMessage != "" {
+			message = envMessage
+		}
+		data = nilData(envData)
+	}
+
+	if status == 0 {
+		status = fasthttp.StatusOK
+	}
+	if code == "" {
+		code = CodeOK
+		if status == fasthttp.StatusCreated {
+			code = CodeCreated
+		}
+	}
+
+	b.responder.Success(ctx, status, code, message, data)
+}
+
+// nilData returns nil for a nil pointer, and data unchanged otherwise.
+func nilData(data any) any {
+	if v := reflect.ValueOf(data); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil
+	}
+
+	return data
+}
+
+var (
+	bytesType  = reflect.TypeOf([]byte(nil))
+	readerType = reflect.TypeOf((*io.Reader)(nil)).Elem()
+)
+
+// RawResponse is handler data written as the response body as-is, without the envelope.
+type RawResponse interface {
+	ContentType() string
+	Body() io.Reader
+}
+
+// Raw returns a RawResponse serving body with the given content type, e.g. for CSV exports.
+func Raw(contentType string, body []byte) RawResponse {
+	return rawBytes{contentType: contentType, body: body}
+}
+
+type rawBytes struct {
+	contentType string
+	body        []byte
+}
+
+func (r rawBytes) ContentType() string { return r.contentType }
+func (r rawBytes) Body() io.Reader     { return bytes.NewReader(r.body) }
+
+// respondRaw writes []byte, io.Reader or RawResponse data as the body; zero status means 200.
+// Readers are streamed and closed afterwards when they implement io.Closer.
+func (b *binding) respondRaw(ctx *fasthttp.RequestCtx, status int, data any) {
+	if status == 0 {
+		status = fasthttp.StatusOK
+	}
+	ctx.SetStatusCode(status)
+	ctx.SetContentType("application/octet-stream")
+
+	switch v := data.(type) {
+	case rawBytes:
+		ctx.SetContentType(v.contentType)
+		ctx.SetBody(v.body)
+	case RawResponse:
+		ctx.SetContentType(v.ContentType())
+		if body := v.Body(); body != nil {
+			ctx.SetBodyStream(body, -1)
+		}
+	case []byte:
+		ctx.SetBody(v)
+	case io.Reader:
+		ctx.SetBodyStream(v, -1)
+	}
+}
+
+// FieldErrors is an error a handler returns to reject a request field by field, mapping each invalid
+// field to its message. It is sent as a 422 through the responder's ValidationError, ahead of any ErrorMapper.
+type FieldErrors map[string]string
+
+// Error lists the invalid fields in name order.
+func (e FieldErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + e[name]
+	}
+
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// respondError writes err through the responder using the status, code and message derived from it
+// by the configured error mapper, or ExtractErrorInfo by default. FieldErrors go to ValidationError.
+func (b *binding) respondError(ctx *fasthttp.RequestCtx, err error) {
+	ctx.SetUserValue(handlerErrorKey, err)
+
+	var fields FieldErrors
+	if errors.As(err, &fields) {
+		respondValidation(b.responder, ctx, fields)
+		return
+	}
+
+	status, code, message := b.errorInfo(err)
+	if b.logger != nil {
+		b.logger.Error("routek: handler error",
+			"method", string(ctx.Method()), "path", string(ctx.Path()), "status", status, "error", err)
+	}
+	setRetryAfter(ctx, err)
+	b.responder.Error(ctx, status, code, message, err)
+}
+
+// errorInfo derives the status, code and message for err from the configured error mapper or, by
+// default, from ExtractErrorInfo with the group's default code for errors that are not errk errors.
+func (b *binding) errorInfo(err error) (int, Code, string) {
+	if b.mapError != nil {
+		return b.mapError(err)
+	}
+
+	status, code, message := ExtractErrorInfo(err)
+	var errkErr *errk.Error
+	if b.defaultCode != "" && !errors.As(err, &errkErr) {
+		code = b.defaultCode
+	}
+
+	return status, code, message
+}
+
+// handlerErrorKey is the user value under which the error returned by a handler is stored.
+const handlerErrorKey = "routek.error"
+
+// handlerError returns the error the route's handler returned, if any.
+func handlerError(ctx *fasthttp.RequestCtx) error {
+	err, _ := ctx.UserValue(handlerErrorKey).(error)
+	return err
+}
+
+// ExtractErrorInfo extracts HTTP status, code, and message from an errk.Error anywhere in err's chain,
+// taking the status from its "http_status" metadata. Errors without one yield 500 INTERNAL_ERROR.
+// It is the mapping handlers use when Config.ErrorMapper is not set, exported for middleware that
+// answers errors itself.
+func ExtractErrorInfo(err error) (int, Code, string) {
+	var errkErr *errk.Error
+	if errors.As(err, &errkErr) {
+		status := fasthttp.StatusInternalServerError
+		if s, ok := errkErr.Metadata()["http_status"].(int); ok {
+			status = s
+		}
+		code := Code(errkErr.Code())
+		message := errkErr.Message()
+		return status, code, message
+	}
+	return fasthttp.StatusInternalServerError, CodeInternalError, "internal server error"
+}
+
+// RetryAfterKey is the errk metadata key whose value, a time.Duration or a number of seconds, is sent
+// as the Retry-After header when the error is answered.
+const RetryAfterKey = "retry_after"
+
+// setRetryAfter sets the Retry-After header from err's RetryAfterKey metadata, rounding durations up to
+// whole seconds. Errors without it, or with a negative value, leave the header unset.
+func setRetryAfter(ctx *fasthttp.RequestCtx, err error) {
+	var errkErr *errk.Error
+	if !errors.As(err, &errkErr) {
+		return
+	}
+
+	var seconds int64
+	switch v := errkErr.Metadata()[RetryAfterKey].(type) {
+	case time.Duration:
+		seconds = int64((v + time.Second - 1) / time.Second)
+	case int:
+		seconds = int64(v)
+	case int64:
+		seconds = v
+	case float64:
+		seconds = int64(math.Ceil(v))
+	default:
+		return
+	}
+
+	if seconds >= 0 {
+		ctx.Response.Header.Set("Retry-After", strconv.FormatInt(seconds, 10))
+	}
+}
@@ -0,0 +1,192 @@
+package routek
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type benchHandlers struct{}
+
+func (benchHandlers) Direct(ctx *fasthttp.RequestCtx) (any, error) {
+	return "ok", nil
+}
+
+// Reflected has a result shape without a fast path, so it is invoked through reflect.Value.Call.
+func (benchHandlers) Reflected(ctx *fasthttp.RequestCtx) (string, error) {
+	return "ok", nil
+}
+
+// handlerAllocs returns the allocations of one request to the named benchHandlers method.
+func handlerAllocs(tb testing.TB, name string) float64 {
+	tb.Helper()
+
+	handler, err := buildHandler(benchHandlers{}, name, &binding{responder: NewResponder(false)})
+	if err != nil {
+		tb.Fatalf("buildHandler(%s): %v", name, err)
+	}
+
+	var ctx fasthttp.RequestCtx
+	return testing.AllocsPerRun(100, func() {
+		ctx.Response.Reset()
+		handler(&ctx)
+	})
+}
+
+func TestFastPathAllocatesLess(t *testing.T) {
+	direct, reflected := handlerAllocs(t, "Direct"), handlerAllocs(t, "Reflected")
+	if direct >= reflected {
+		t.Errorf("direct call allocates %v per request, reflected call %v; want fewer", direct, reflected)
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	for _, name := range []string{"Direct", "Reflected"} {
+		b.Run(name, func(b *testing.B) {
+			handler, err := buildHandler(benchHandlers{}, name, &binding{responder: NewResponder(false)})
+			if err != nil {
+				b.Fatalf("buildHandler(%s): %v", name, err)
+			}
+
+			var ctx fasthttp.RequestCtx
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				ctx.Response.Reset()
+				handler(&ctx)
+			}
+		})
+	}
+}
+
+type itemHandlers struct{}
+
+func (itemHandlers) Get(ctx *fasthttp.RequestCtx) (any, error) {
+	return map[string]string{"id": "7", "name": "widget"}, nil
+}
+
+type deleteRequest struct {
+	Reason string `json:"reason"`
+}
+
+func (itemHandlers) Delete(ctx *fasthttp.RequestCtx, req *deleteRequest) (any, error) {
+	return req.Reason, nil
+}
+
+const itemRoutes = `
+items:
+  route:
+    - methods: [get, head]
+      path: /items/{id}
+      handler: Get
+    - delete: /items/{id}
+      handler: Delete
+`
+
+func itemConfig() Config {
+	return Config{
+		RouteFile: "routes.yaml",
+		Loader:    func(string) ([]byte, error) { return []byte(itemRoutes), nil },
+		Handlers:  map[string]any{"items": itemHandlers{}},
+	}
+}
+
+func TestHeadHasNoBody(t *testing.T) {
+	cfg := itemConfig()
+
+	get, err := TestInvoke(cfg, fasthttp.MethodGet, "/items/7", nil)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	head, err := TestInvoke(cfg, fasthttp.MethodHead, "/items/7", nil)
+	if err != nil {
+		t.Fatalf("HEAD: %v", err)
+	}
+
+	if head.StatusCode() != get.StatusCode() {
+		t.Errorf("HEAD status %d, want GET's %d", head.StatusCode(), get.StatusCode())
+	}
+	if len(head.Body()) != 0 {
+		t.Errorf("HEAD body %q, want none", head.Body())
+	}
+	if got, want := head.Header.ContentLength(), len(get.Body()); got != want {
+		t.Errorf("HEAD Content-Length %d, want the GET body's %d", got, want)
+	}
+	if got, want := string(head.Header.ContentType()), string(get.Header.ContentType()); got != want {
+		t.Errorf("HEAD Content-Type %q, want %q", got, want)
+	}
+}
+
+func TestDeleteWithBody(t *testing.T) {
+	cfg := itemConfig()
+
+	resp, err := TestInvoke(cfg, fasthttp.MethodDelete, "/items/7", []byte(`{"reason":"duplicate"}`))
+	if err != nil {
+		t.Fatalf("DELETE: %v", err)
+	}
+	if resp.StatusCode() != fasthttp.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode())
+	}
+	if data := decodeEnvelope(t, resp.Body())["data"]; data != "duplicate" {
+		t.Errorf("data %v, want the decoded reason", data)
+	}
+	if code := decodeEnvelope(t, resp.Body())["code"]; code != string(CodeOK) {
+		t.Errorf("code %v, want %s", code, CodeOK)
+	}
+}
+
+type user struct {
+	Name string `json:"name"`
+}
+
+type nilHandlers struct{}
+
+func (nilHandlers) Find(ctx *fasthttp.RequestCtx) (*user, error) {
+	return nil, nil
+}
+
+func (nilHandlers) Accept(ctx *fasthttp.RequestCtx) (any, error) {
+	return Result{Status: fasthttp.StatusAccepted, Data: (*user)(nil)}, nil
+}
+
+const nilRoutes = `
+users:
+  route:
+    - get: /users/missing
+      handler: Find
+    - post: /users/jobs
+      handler: Accept
+`
+
+func TestNilPointerData(t *testing.T) {
+	rt := newTestRouter(t, nilRoutes, Config{Handlers: map[string]any{"users": nilHandlers{}}})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/users/missing")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	if data, present := decodeEnvelope(t, ctx.Response.Body())["data"]; !present || data != nil {
+		t.Errorf("data %v, want null", data)
+	}
+}
+
+func TestNoContentOnNilData(t *testing.T) {
+	rt := newTestRouter(t, nilRoutes, Config{Handlers: map[string]any{"users": nilHandlers{}}, NoContentOnNilData: true})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/users/missing")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusNoContent {
+		t.Errorf("status %d, want 204", status)
+	}
+	if len(ctx.Response.Body()) != 0 {
+		t.Errorf("body %q, want none", ctx.Response.Body())
+	}
+
+	// A status chosen by the handler is kept, with null data.
+	ctx = serve(rt.Handler, fasthttp.MethodPost, "/users/jobs")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusAccepted {
+		t.Errorf("status %d, want the handler's 202", status)
+	}
+	if data, present := decodeEnvelope(t, ctx.Response.Body())["data"]; !present || data != nil {
+		t.Errorf("data %v, want null", data)
+	}
+}
@@ -0,0 +1,62 @@
+package routek
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// routeHeaders converts a route's headers value, a map of header names to string values.
+func routeHeaders(val any) (map[string]string, error) {
+	v, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New(`route headers must be a map such as {Cache-Control: "no-store"}`)
+	}
+
+	headers := make(map[string]string, len(v))
+	for name, value := range v {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("route header %q must be a string", name)
+		}
+		headers[name] = s
+	}
+	return headers, nil
+}
+
+// checkHeaders rejects header names that are not HTTP tokens and values that could split the response.
+func checkHeaders(headers map[string]string) error {
+	for name, value := range headers {
+		if !isMethodToken(name) {
+			return fmt.Errorf("route header name %q is not a valid HTTP header name", name)
+		}
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("route header %q must not contain line breaks", name)
+		}
+	}
+
+	return nil
+}
+
+// headersMiddleware sets a route's static headers before the handler runs, so success and error
+// responses both carry them unless the handler replaces them.
+func headersMiddleware(headers map[string]string) Middleware {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			for _, name := range names {
+				ctx.Response.Header.Set(name, headers[name])
+			}
+
+			next(ctx)
+		}
+	}
+}
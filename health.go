@@ -0,0 +1,37 @@
+package routek
+
+import "github.com/valyala/fasthttp"
+
+// DefaultHealthCheckPath is used when HealthCheckConfig.Path is empty.
+const DefaultHealthCheckPath = "/healthz"
+
+// HealthCheckConfig enables a GET health endpoint that does not need an entry in the route file.
+type HealthCheckConfig struct {
+	// Path defaults to DefaultHealthCheckPath.
+	Path string
+	// Probe, if set, is called on every request; a non-nil error answers 503 instead of 200, with a
+	// Retry-After header when the error carries RetryAfterKey metadata.
+	Probe func() error
+}
+
+func (c *HealthCheckConfig) path() string {
+	if c.Path == "" {
+		return DefaultHealthCheckPath
+	}
+
+	return c.Path
+}
+
+func (c *HealthCheckConfig) handler(responder Responder) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		if c.Probe != nil {
+			if err := c.Probe(); err != nil {
+				setRetryAfter(ctx, err)
+				responder.Error(ctx, fasthttp.StatusServiceUnavailable, CodeServiceUnavailable, "service unavailable", err)
+				return
+			}
+		}
+
+		responder.Success(ctx, fasthttp.StatusOK, CodeOK, "ok", map[string]string{"status": "ok"})
+	}
+}
@@ -0,0 +1,48 @@
+package routek
+
+import (
+	"github.com/valyala/fasthttp"
+)
+
+// TestInvoke builds the router from cfg and serves a single in-process request through it, returning
+// the response for assertions. The request goes through the real registration and routing path, so
+// path parameters, middleware and the responder all apply. A non-empty body is sent as
+// application/json. A response written after a route timeout is the 504 the client would see, and a
+// HEAD response keeps its status, headers and Content-Length but has no body, as the server sends it.
+func TestInvoke(cfg Config, method, path string, body []byte) (*fasthttp.Response, error) {
+	rt, err := NewRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(path)
+	if len(body) > 0 {
+		req.Header.SetContentType(mimeJSON)
+		req.SetBody(body)
+	}
+
+	// Init gives the ctx what a server would, so Context(ctx) and route timeouts work.
+	var ctx fasthttp.RequestCtx
+	ctx.Init(&req, nil, nil)
+	rt.Handler(&ctx)
+
+	resp := &fasthttp.Response{}
+	if timeout := ctx.LastTimeoutErrorResponse(); timeout != nil {
+		timeout.CopyTo(resp)
+	} else {
+		// Body drains a streamed body, which CopyTo would not copy.
+		ctx.Response.Body()
+		ctx.Response.CopyTo(resp)
+	}
+
+	// Mirror what the server writes: a Content-Length for the body, and no body for HEAD requests.
+	resp.Header.SetContentLength(len(resp.Body()))
+	if ctx.IsHead() {
+		resp.SkipBody = true
+		resp.ResetBody()
+	}
+
+	return resp, nil
+}
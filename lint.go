@@ -0,0 +1,213 @@
+package routek
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// LintSeverity grades a LintIssue.
+type LintSeverity string
+
+const (
+	// LintError marks a conflict that makes NewRouter fail.
+	LintError LintSeverity = "error"
+	// LintWarning marks routes that register fine but overlap, so some requests reach only one of them.
+	LintWarning LintSeverity = "warning"
+)
+
+// LintIssue is a problem found by Lint. Routes lists the routes involved, with their full paths.
+type LintIssue struct {
+	Severity LintSeverity
+	Message  string
+	Routes   []RegisteredRoute
+}
+
+// Lint looks for conflicting and shadowing paths in the route files of cfg without building the router
+// or binding handlers. Errors are paths the router refuses, such as two parameters with different
+// names at the same position. Warnings are paths that overlap, such as /users/new and /users/{id},
+// where the more specific route takes the requests both could serve. A route file that cannot be
+// loaded is reported as a single error.
+func Lint(cfg Config) []LintIssue {
+	doc, err := loadRouteDocument(cfg)
+	if err == nil {
+		doc, err = doc.flatten()
+	}
+	if err != nil {
+		return []LintIssue{{Severity: LintError, Message: err.Error()}}
+	}
+
+	routes := lintRoutes(cfg, doc)
+
+	var issues []LintIssue
+	trial := router.New()
+	noop := func(*fasthttp.RequestCtx) {}
+	for i, route := range routes {
+		// Register in build order, so the route the router refuses is the one NewRouter would refuse.
+		if err := register(trial, route.Method, route.Path, noop); err != nil {
+			// The route it conflicts with matches the same requests, up to parameter names.
+			var involved []RegisteredRoute
+			for _, earlier := range routes[:i] {
+				if overlap, _ := pathsOverlap(earlier.Path, route.Path); earlier.Method == route.Method && overlap == overlapSame {
+					involved = append(involved, earlier)
+				}
+			}
+			involved = append(involved, route)
+			issues = append(issues, LintIssue{Severity: LintError, Message: err.Error(), Routes: involved})
+			continue
+		}
+
+		for _, earlier := range routes[:i] {
+			if earlier.Method != route.Method {
+				continue
+			}
+
+			for _, a := range optionalVariants(earlier.Path) {
+				for _, b := range optionalVariants(route.Path) {
+					message, ok := shadowing(route.Method, a, b)
+					if ok {
+						issues = append(issues, LintIssue{Severity: LintWarning, Message: message, Routes: []RegisteredRoute{earlier, route}})
+					}
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// lintRoutes returns the enabled routes of a flattened document with their full paths, in the order
+// build registers them.
+func lintRoutes(cfg Config, doc Document) []RegisteredRoute {
+	groups := make([]string, 0, len(doc))
+	for group := range doc {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	var routes []RegisteredRoute
+	for _, group := range groups {
+		g := doc[group]
+		prefix, err := expandEnv(g.Prefix)
+		if err != nil {
+			continue
+		}
+		prefix = joinPath(basePrefix(cfg), prefix)
+
+		for _, s := range g.Static {
+			if routePath, err := expandEnv(s.Path); err == nil && s.Path != "" {
+				full, _ := staticPath(prefix, routePath)
+				routes = append(routes, RegisteredRoute{Method: fasthttp.MethodGet, Path: full, Group: group})
+			}
+		}
+
+		for _, r := range g.Routes {
+			if r.Enabled != "" {
+				if enabled, err := routeEnabled(r.Enabled); err == nil && !enabled {
+					continue
+				}
+			}
+
+			routePath, err := expandEnv(r.Path)
+			if err != nil {
+				continue
+			}
+			path := catchAll(joinPath(prefix, routePath))
+			for _, method := range r.Methods {
+				routes = append(routes, RegisteredRoute{Method: method, Path: path, Group: group, Handler: r.handlerLabel()})
+			}
+			for _, alias := range r.Aliases {
+				if aliasPath, err := expandEnv(alias); err == nil {
+					for _, method := range r.Methods {
+						routes = append(routes, RegisteredRoute{Method: method, Path: catchAll(joinPath(prefix, aliasPath)), Group: group, Handler: r.handlerLabel(), AliasOf: path})
+					}
+				}
+			}
+		}
+	}
+
+	return routes
+}
+
+// optionalVariants returns the paths a route with a trailing optional parameter serves.
+func optionalVariants(path string) []string {
+	base, ok, err := optionalBase(path)
+	if err != nil || !ok {
+		return []string{path}
+	}
+
+	return []string{base, strings.TrimSuffix(path, "?}") + "}"}
+}
+
+// Outcomes of pathsOverlap.
+const (
+	overlapNone      = iota
+	overlapSame      // the paths match the same requests
+	overlapShadowing // some requests match both, and the router prefers one of them
+)
+
+// pathsOverlap compares two paths segment by segment, treating parameter segments as matching anything
+// and a trailing catch-all as matching the rest of the path. For shadowing paths it also reports
+// whether the router prefers a: at the first segment where only one path is literal, that one wins.
+func pathsOverlap(a, b string) (int, bool) {
+	sa, sb := strings.Split(strings.Trim(a, "/"), "/"), strings.Split(strings.Trim(b, "/"), "/")
+
+	result, preferA := overlapSame, false
+	prefer := func(isA bool) {
+		if result == overlapSame {
+			result, preferA = overlapShadowing, isA
+		}
+	}
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		if i >= len(sa) || i >= len(sb) {
+			return overlapNone, false
+		}
+
+		ca, cb := isCatchAllSegment(sa[i]), isCatchAllSegment(sb[i])
+		switch {
+		case ca && cb:
+			return result, preferA
+		case ca || cb:
+			prefer(cb)
+			return result, preferA
+		}
+
+		pa, pb := isParamSegment(sa[i]), isParamSegment(sb[i])
+		switch {
+		case pa && pb:
+		case pa && sb[i] == "", pb && sa[i] == "":
+			// A parameter never matches an empty segment.
+			return overlapNone, false
+		case pa || pb:
+			prefer(pb)
+		case sa[i] != sb[i]:
+			return overlapNone, false
+		}
+	}
+
+	return result, preferA
+}
+
+// shadowing describes how the router splits the requests two overlapping paths could both serve.
+func shadowing(method, a, b string) (string, bool) {
+	overlap, preferA := pathsOverlap(a, b)
+	if overlap != overlapShadowing {
+		return "", false
+	}
+
+	if !preferA {
+		a, b = b, a
+	}
+	return fmt.Sprintf("%s %s takes precedence over %s for the requests both match", method, a, b), true
+}
+
+func isParamSegment(segment string) bool {
+	return strings.Contains(segment, "{")
+}
+
+func isCatchAllSegment(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, ":*}")
+}
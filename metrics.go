@@ -0,0 +1,38 @@
+package routek
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// MetricsRecorder observes every request served by a registered route. Route is the declared path
+// pattern (e.g. /users/{id}), never the expanded request path, so label cardinality stays bounded.
+type MetricsRecorder interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// metricsMiddleware records the duration and final status of each request, including the time spent
+// in middleware and writing the response, and the 504 of a route timeout.
+func metricsMiddleware(m MetricsRecorder, method, route string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+			m.ObserveRequest(method, route, responseStatus(ctx), time.Since(start))
+		}
+	}
+}
+
+// afterResponseMiddleware calls hook once the route's response is final, with the status it carries,
+// including a status written by a route timeout.
+func afterResponseMiddleware(hook func(ctx *fasthttp.RequestCtx, group, handler string, status int, duration time.Duration), group, handler string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			start := time.Now()
+			next(ctx)
+
+			hook(ctx, group, handler, responseStatus(ctx), time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package routek
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type observation struct {
+	method, route string
+	status        int
+	duration      time.Duration
+}
+
+// recordingMetrics is a MetricsRecorder keeping every observation.
+type recordingMetrics struct {
+	mu           sync.Mutex
+	observations []observation
+}
+
+func (m *recordingMetrics) ObserveRequest(method, route string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.observations = append(m.observations, observation{method, route, status, duration})
+}
+
+type metricsHandlers struct{}
+
+func (metricsHandlers) Show(ctx *fasthttp.RequestCtx) (any, error) {
+	time.Sleep(5 * time.Millisecond)
+	return ctx.UserValue("id"), nil
+}
+
+func (metricsHandlers) Fail(ctx *fasthttp.RequestCtx) (any, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func (metricsHandlers) Crash(ctx *fasthttp.RequestCtx) (any, error) {
+	panic("crash")
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := &recordingMetrics{}
+	rt := newTestRouter(t, `
+users:
+  prefix: /users
+  route:
+    - get: /{id}
+      handler: Show
+    - post: /{id}/sync
+      handler: Fail
+    - delete: /{id}
+      handler: Crash
+`, Config{
+		Handlers:      map[string]any{"users": metricsHandlers{}},
+		Metrics:       metrics,
+		RecoverPanics: true,
+	})
+
+	serve(rt.Handler, fasthttp.MethodGet, "/users/7")
+	serve(rt.Handler, fasthttp.MethodPost, "/users/7/sync")
+	serve(rt.Handler, fasthttp.MethodDelete, "/users/7")
+
+	want := []observation{
+		{method: fasthttp.MethodGet, route: "/users/{id}", status: fasthttp.StatusOK},
+		{method: fasthttp.MethodPost, route: "/users/{id}/sync", status: fasthttp.StatusInternalServerError},
+		{method: fasthttp.MethodDelete, route: "/users/{id}", status: fasthttp.StatusInternalServerError},
+	}
+	if len(metrics.observations) != len(want) {
+		t.Fatalf("observed %+v, want %d requests", metrics.observations, len(want))
+	}
+	for i, got := range metrics.observations {
+		if got.method != want[i].method || got.route != want[i].route || got.status != want[i].status {
+			t.Errorf("observation %d = %s %s %d, want %s %s %d", i, got.method, got.route, got.status, want[i].method, want[i].route, want[i].status)
+		}
+		if got.duration <= 0 {
+			t.Errorf("observation %d has duration %v", i, got.duration)
+		}
+	}
+	if d := metrics.observations[0].duration; d < 5*time.Millisecond {
+		t.Errorf("success duration %v does not cover the handler's 5ms", d)
+	}
+}
@@ -0,0 +1,16 @@
+package routek
+
+import "github.com/valyala/fasthttp"
+
+// Middleware wraps a handler to add behaviour before or after it runs. It is an alias, so existing
+// func(fasthttp.RequestHandler) fasthttp.RequestHandler values and their maps and slices assign as-is.
+type Middleware = func(fasthttp.RequestHandler) fasthttp.RequestHandler
+
+// chain wraps handler so that the first middleware is the outermost one and runs first.
+func chain(handler fasthttp.RequestHandler, middleware ...Middleware) fasthttp.RequestHandler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+
+	return handler
+}
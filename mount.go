@@ -0,0 +1,35 @@
+package routek
+
+import (
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+// StripPrefix returns a handler that removes prefix from the request path before calling next, for
+// mounting a router built without Config.MountPath under a sub-path of a larger application. A request
+// for the prefix itself reaches next as "/". Requests outside the prefix are answered with a 404 through
+// responder, which should be the router's Config.Responder; nil uses NewResponder(false).
+func StripPrefix(prefix string, next fasthttp.RequestHandler, responder Responder) fasthttp.RequestHandler {
+	if responder == nil {
+		responder = defaultResponder
+	}
+	prefix = joinPath(prefix, "")
+	if prefix == "" {
+		return next
+	}
+
+	return func(ctx *fasthttp.RequestCtx) {
+		rest, ok := strings.CutPrefix(string(ctx.Path()), prefix)
+		if !ok || (rest != "" && rest[0] != '/') {
+			responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "Not Found", nil)
+			return
+		}
+
+		if rest == "" {
+			rest = "/"
+		}
+		ctx.URI().SetPath(rest)
+		next(ctx)
+	}
+}
@@ -0,0 +1,50 @@
+package routek
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+// plainResponder answers with the code as a plain-text body, to tell its responses from the default envelope.
+type plainResponder struct{}
+
+func (plainResponder) Success(ctx *fasthttp.RequestCtx, status int, code Code, message string, data any) {
+	ctx.SetStatusCode(status)
+	ctx.SetBodyString(string(code))
+}
+
+func (plainResponder) Error(ctx *fasthttp.RequestCtx, status int, code Code, message string, err error) {
+	ctx.SetStatusCode(status)
+	ctx.SetBodyString(string(code))
+}
+
+func TestStripPrefix(t *testing.T) {
+	rt := newTestRouter(t, `
+api:
+  route:
+    - get: /ping
+      handler: Ping
+`, Config{Handlers: map[string]any{"api": probeHandlers{}}, Responder: plainResponder{}})
+	mounted := StripPrefix("/api/", rt.Handler, plainResponder{})
+
+	tests := []struct {
+		uri    string
+		status int
+		body   string
+	}{
+		{"/api/ping", fasthttp.StatusOK, string(CodeOK)},
+		{"/api", fasthttp.StatusNotFound, string(CodeNotFound)},
+		{"/apiary/ping", fasthttp.StatusNotFound, string(CodeNotFound)},
+		{"/ping", fasthttp.StatusNotFound, string(CodeNotFound)},
+	}
+	for _, tt := range tests {
+		ctx := serve(mounted, fasthttp.MethodGet, tt.uri)
+		if status := ctx.Response.StatusCode(); status != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.uri, status, tt.status)
+		}
+		if body := string(ctx.Response.Body()); body != tt.body {
+			t.Errorf("GET %s: body %q, want %q from the given responder", tt.uri, body, tt.body)
+		}
+	}
+}
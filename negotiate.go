@@ -0,0 +1,238 @@
+package routek
+
+import (
+	"encoding/xml"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	mimeJSON    = "application/json"
+	mimeXML     = "application/xml"
+	mimeTextXML = "text/xml"
+)
+
+// NegotiatingResponder writes the standard envelope as JSON or XML depending on the request's Accept
+// header, defaulting to JSON when neither is preferred.
+type NegotiatingResponder struct {
+	debug bool
+	json  *JSONResponder
+}
+
+// NewNegotiatingResponder creates a content-negotiating responder; debug=true will include error details in responses.
+func NewNegotiatingResponder(debug bool, opts ...ResponderOption) *NegotiatingResponder {
+	return &NegotiatingResponder{debug: debug, json: NewResponder(debug, opts...)}
+}
+
+func (r *NegotiatingResponder) debugging() Responder {
+	json := *r.json
+	json.debug = true
+	return &NegotiatingResponder{debug: true, json: &json}
+}
+
+func (r *NegotiatingResponder) successOnNilError() bool {
+	return r.json.opts.successOnNil
+}
+
+func (r *NegotiatingResponder) stampsRequestID() bool {
+	return r.json.opts.requestID
+}
+
+// Success sends a successful Response in the negotiated format.
+func (r *NegotiatingResponder) Success(ctx *fasthttp.RequestCtx, status int, code Code, message string, data any) {
+	mime := negotiate(string(ctx.Request.Header.Peek("Accept")), mimeJSON, mimeXML, mimeTextXML)
+	if mime != mimeXML && mime != mimeTextXML {
+		r.json.Success(ctx, status, code, message, data)
+		return
+	}
+
+	resp := successResponse(code, message, data)
+	if r.json.opts.notModified(ctx, status, mime, resp) {
+		return
+	}
+	r.json.opts.stamp(ctx, &resp)
+	r.writeXML(ctx, mime, status, resp)
+}
+
+// Error sends an error Response in the negotiated format.
+func (r *NegotiatingResponder) Error(ctx *fasthttp.RequestCtx, status int, code Code, message string, err error) {
+	mime := negotiate(string(ctx.Request.Header.Peek("Accept")), mimeJSON, mimeXML, mimeTextXML)
+	if mime != mimeXML && mime != mimeTextXML {
+		r.json.Error(ctx, status, code, message, err)
+		return
+	}
+
+	status, resp := errorResponse(r.debug, status, code, message, err)
+	r.json.opts.stamp(ctx, &resp)
+	r.writeXML(ctx, mime, status, resp)
+}
+
+// Created sends a 201 Response in the negotiated format and sets the Location header.
+func (r *NegotiatingResponder) Created(ctx *fasthttp.RequestCtx, location string, data any) {
+	ctx.Response.Header.Set("Location", location)
+	r.Success(ctx, fasthttp.StatusCreated, CodeCreated, "success", data)
+}
+
+// ValidationError sends a 422 validation Response in the negotiated format.
+func (r *NegotiatingResponder) ValidationError(ctx *fasthttp.RequestCtx, fields map[string]string) {
+	mime := negotiate(string(ctx.Request.Header.Peek("Accept")), mimeJSON, mimeXML, mimeTextXML)
+	if mime != mimeXML && mime != mimeTextXML {
+		r.json.ValidationError(ctx, fields)
+		return
+	}
+
+	resp := validationResponse(fields)
+	r.json.opts.stamp(ctx, &resp)
+	r.writeXML(ctx, mime, fasthttp.StatusUnprocessableEntity, resp)
+}
+
+// NoContent sends a 204 with no body, which needs no negotiation.
+func (r *NegotiatingResponder) NoContent(ctx *fasthttp.RequestCtx) {
+	r.json.NoContent(ctx)
+}
+
+// xmlResponse is the XML form of Response.
+type xmlResponse struct {
+	XMLName   xml.Name `xml:"response"`
+	Message   string   `xml:"message"`
+	Code      Code     `xml:"code"`
+	Data      *xmlData `xml:"data,omitempty"`
+	Timestamp int64    `xml:"timestamp"`
+	RequestID string   `xml:"request_id,omitempty"`
+}
+
+// writeXML marshals resp as XML, falling back to a minimal internal error body when marshaling fails.
+func (r *NegotiatingResponder) writeXML(ctx *fasthttp.RequestCtx, mime string, status int, resp Response[any]) {
+	payload := xmlResponse{
+		Message:   resp.Message,
+		Code:      resp.Code,
+		Timestamp: resp.Timestamp,
+		RequestID: resp.RequestID,
+	}
+	if !r.json.opts.omitEmptyData || !emptyData(resp.Data) {
+		payload.Data = &xmlData{resp.Data}
+	}
+
+	body, err := xml.Marshal(payload)
+	if err != nil {
+		logResponseError(ctx, "failed to marshal response", err)
+		ctx.Response.Header.Set("Content-Type", mime)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+		ctx.SetBodyString(
+			fmt.Sprintf(
+				`<response><message>internal server error</message><code>INTERNAL_ERROR</code><data></data><timestamp>%d</timestamp></response>`,
+				time.Now().UTC().UnixMilli(),
+			),
+		)
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", mime)
+	ctx.SetStatusCode(status)
+	r.json.opts.setBody(ctx, body)
+}
+
+// xmlData marshals arbitrary envelope data, including the maps and slices that encoding/xml cannot
+// encode on its own: map entries become elements named after their keys, slice items become <item> elements.
+type xmlData struct {
+	v any
+}
+
+func (d xmlData) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if d.v == nil {
+		return e.EncodeElement("", start)
+	}
+
+	v := reflect.ValueOf(d.v)
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, key := range keys {
+			elem := xml.StartElement{Name: xml.Name{Local: key.String()}}
+			if err := e.EncodeElement(xmlData{v.MapIndex(key).Interface()}, elem); err != nil {
+				return err
+			}
+		}
+
+		return e.EncodeToken(start.End())
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8:
+		if err := e.EncodeToken(start); err != nil {
+			return err
+		}
+
+		item := xml.StartElement{Name: xml.Name{Local: "item"}}
+		for i := 0; i < v.Len(); i++ {
+			if err := e.EncodeElement(xmlData{v.Index(i).Interface()}, item); err != nil {
+				return err
+			}
+		}
+
+		return e.EncodeToken(start.End())
+	default:
+		return e.EncodeElement(d.v, start)
+	}
+}
+
+// negotiate picks the offer the Accept header prefers most, honouring q-values and wildcards.
+// An empty header accepts the first offer; "" is returned when no offer is acceptable.
+func negotiate(accept string, offers ...string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(accept, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// acceptQuality returns the q-value the Accept header assigns to mime, using the most specific matching range.
+func acceptQuality(accept, mime string) float64 {
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, param := range params[1:] {
+			if name, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(name) == "q" {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		var level int
+		switch {
+		case mediaRange == mime:
+			level = 2
+		case mediaRange == "*/*":
+			level = 0
+		case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(mime, strings.TrimSuffix(mediaRange, "*")):
+			level = 1
+		default:
+			continue
+		}
+
+		if level > specificity {
+			quality, specificity = q, level
+		}
+	}
+
+	return quality
+}
@@ -0,0 +1,354 @@
+package routek
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// OpenAPIInfo is the info object of a generated OpenAPI document.
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// GenerateOpenAPI builds the routes described by cfg, with the same checks as Validate, and returns
+// an OpenAPI 3.0 document for them as JSON. Routes are tagged with their group, path parameters
+// carry their constraints, and routes naming a request or response schema reference the matching
+// entry of cfg.Schemas. Every response is described with the standard envelope.
+//
+// Schemas are derived from flat structs: fields of basic types, slices and maps are described, while
+// nested structs are left as plain objects.
+func GenerateOpenAPI(cfg Config, info OpenAPIInfo) ([]byte, error) {
+	routes, err := build(cfg, func() (Document, error) {
+		return loadRouteDocument(cfg)
+	}, router.New(), responderFor(cfg), cfg.CollectErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := map[string]any{"Response": envelopeSchema()}
+	for name, value := range cfg.Schemas {
+		schema, err := structSchema(value)
+		if err != nil {
+			return nil, fmt.Errorf("routek: schema %q: %w", name, err)
+		}
+		schemas[name] = schema
+	}
+
+	paths := make(map[string]map[string]any)
+	operationIDs := make(map[string]int)
+	for _, route := range routes {
+		// Static file routes serve assets, not API operations, and OpenAPI cannot describe custom methods.
+		if route.route == nil && route.Group != "" || !openAPIMethods[route.Method] {
+			continue
+		}
+
+		// OpenAPI has no optional path parameters, so a trailing {name?} is described as two paths.
+		variants := []RegisteredRoute{route}
+		if base, ok, _ := optionalBase(route.Path); ok {
+			without := route
+			without.Path = base
+			variants = []RegisteredRoute{without, route}
+		}
+
+		for _, variant := range variants {
+			path := openAPIPath(variant.Path)
+			if paths[path] == nil {
+				paths[path] = make(map[string]any)
+			}
+
+			op, err := operation(cfg, variant)
+			if err != nil {
+				return nil, err
+			}
+			// Operation IDs must be unique, but one handler may serve several routes.
+			if id, ok := op["operationId"].(string); ok {
+				if operationIDs[id]++; operationIDs[id] > 1 {
+					op["operationId"] = fmt.Sprintf("%s_%d", id, operationIDs[id])
+				}
+			}
+			paths[path][strings.ToLower(variant.Method)] = op
+		}
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       info.Title,
+			"version":     info.Version,
+			"description": info.Description,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}, "", "  ")
+}
+
+// openAPIMethods are the methods an OpenAPI 3.0 path item can describe.
+var openAPIMethods = map[string]bool{
+	fasthttp.MethodGet:     true,
+	fasthttp.MethodPut:     true,
+	fasthttp.MethodPost:    true,
+	fasthttp.MethodDelete:  true,
+	fasthttp.MethodOptions: true,
+	fasthttp.MethodHead:    true,
+	fasthttp.MethodPatch:   true,
+	fasthttp.MethodTrace:   true,
+}
+
+// operation describes a single registered route.
+func operation(cfg Config, route RegisteredRoute) (map[string]any, error) {
+	op := map[string]any{
+		"responses": map[string]any{
+			"200":     envelopeResponse("success", nil),
+			"default": envelopeResponse("error", nil),
+		},
+	}
+	if route.route == nil {
+		return op, nil
+	}
+
+	r := route.route
+	op["operationId"] = route.Group + "." + route.Handler
+	op["tags"] = []string{route.Group}
+	if route.Deprecated {
+		op["deprecated"] = true
+	}
+	if route.Summary != "" {
+		op["summary"] = route.Summary
+	}
+	if route.Description != "" {
+		op["description"] = route.Description
+	}
+
+	if params := append(pathParameters(route.Path, r.Constraints), queryParameters(r.Query)...); len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if r.Request != "" {
+		if _, ok := cfg.Schemas[r.Request]; !ok {
+			return nil, fmt.Errorf("routek: %s.%s: request schema %q not registered", route.Group, route.Handler, r.Request)
+		}
+		content := make(map[string]any)
+		for _, mediaType := range r.Consumes {
+			content[strings.ToLower(mediaType)] = map[string]any{"schema": schemaRef(r.Request)}
+		}
+		if len(content) == 0 {
+			content[mimeJSON] = map[string]any{"schema": schemaRef(r.Request)}
+		}
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  content,
+		}
+	}
+
+	if r.Response != "" {
+		if _, ok := cfg.Schemas[r.Response]; !ok {
+			return nil, fmt.Errorf("routek: %s.%s: response schema %q not registered", route.Group, route.Handler, r.Response)
+		}
+		op["responses"].(map[string]any)["200"] = envelopeResponse("success", schemaRef(r.Response))
+	}
+
+	return op, nil
+}
+
+// openAPIPath rewrites router parameters such as {id?} and {path:*} into OpenAPI's {id} form.
+func openAPIPath(path string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(path, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(path[start:], '}')
+		if end < 0 {
+			break
+		}
+
+		name := path[start+1 : start+end]
+		if i := strings.IndexAny(name, ":?"); i >= 0 {
+			name = name[:i]
+		}
+		b.WriteString(path[:start] + "{" + name + "}")
+		path = path[start+end+1:]
+	}
+
+	b.WriteString(path)
+	return b.String()
+}
+
+// pathParameters describes the parameters of path, applying the declared constraints.
+func pathParameters(path string, constraints map[string]string) []map[string]any {
+	names := make([]string, 0)
+	for name := range pathParams(path) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   constraintSchema(constraints[name]),
+		})
+	}
+
+	return params
+}
+
+// queryParameters describes a route's required query parameters in declaration order.
+func queryParameters(declared []string) []map[string]any {
+	params := make([]map[string]any, 0, len(declared))
+	for _, decl := range declared {
+		name, kind, _ := strings.Cut(decl, ":")
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "query",
+			"required": true,
+			"schema":   constraintSchema(kind),
+		})
+	}
+
+	return params
+}
+
+// constraintSchema describes the values a constraint type accepts; an empty type accepts any string.
+func constraintSchema(kind string) map[string]any {
+	schema := map[string]any{"type": "string"}
+	switch {
+	case kind == "int":
+		schema = map[string]any{"type": "integer", "format": "int64"}
+	case kind == "uuid":
+		schema["format"] = "uuid"
+	case strings.HasPrefix(kind, "regex:"):
+		schema["pattern"] = "^(?:" + strings.TrimPrefix(kind, "regex:") + ")$"
+	}
+
+	return schema
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// envelopeSchema describes Response.
+func envelopeSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"message":   map[string]any{"type": "string"},
+			"code":      map[string]any{"type": "string"},
+			"data":      map[string]any{},
+			"timestamp": map[string]any{"type": "integer", "format": "int64"},
+			// Only present with WithRequestID, so it is not required.
+			"request_id": map[string]any{"type": "string"},
+		},
+	}
+}
+
+// envelopeResponse describes a response wrapped in the envelope, with data described by data when set.
+func envelopeResponse(description string, data map[string]any) map[string]any {
+	schema := schemaRef("Response")
+	if data != nil {
+		schema = map[string]any{"allOf": []any{
+			schemaRef("Response"),
+			map[string]any{"properties": map[string]any{"data": data}},
+		}}
+	}
+
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{mimeJSON: map[string]any{"schema": schema}},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structSchema describes the exported fields of a struct value, honouring json tags.
+func structSchema(value any) (map[string]any, error) {
+	t := reflect.TypeOf(value)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("must be a struct, got %T", value)
+	}
+
+	properties := make(map[string]any)
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitempty := field.Name, false
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, opts, _ := strings.Cut(tag, ",")
+			if tagName == "-" && opts == "" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+			omitempty = strings.Contains(","+opts+",", ",omitempty,")
+		}
+
+		properties[name] = typeSchema(field.Type)
+		if !omitempty && field.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	return schema, nil
+}
+
+// typeSchema describes a field type; nested structs are plain objects.
+func typeSchema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	default:
+		return map[string]any{"type": "object"}
+	}
+}
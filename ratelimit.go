@@ -0,0 +1,156 @@
+package routek
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RateLimitOptions configures a route's `rate_limit` key in the route file.
+type RateLimitOptions struct {
+	// RPS is the steady number of requests allowed per second.
+	RPS float64
+	// Burst is how many requests may arrive at once; it defaults to RPS rounded up.
+	Burst int
+	// PerClient gives each client, as named by Config.RateLimitKey, its own bucket instead of one per route.
+	PerClient bool
+}
+
+// RateLimitStore holds the token buckets of rate-limited routes. Allow takes a token from the bucket
+// named key, refilled at rps up to burst tokens, and reports how long until one is available when it is
+// empty. Implementations must be safe for concurrent use, e.g. backed by Redis to share limits across
+// instances.
+type RateLimitStore interface {
+	Allow(key string, rps float64, burst int) (ok bool, retryAfter time.Duration)
+}
+
+// NewMemoryRateLimitStore returns a RateLimitStore keeping buckets in memory. Buckets that have refilled
+// are dropped now and then, so per-client keys do not accumulate.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+type memoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	calls   int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket is back to burst tokens
+}
+
+// sweepEvery is how many Allow calls pass between drops of refilled buckets.
+const sweepEvery = 1024
+
+func (s *memoryRateLimitStore) Allow(key string, rps float64, burst int) (bool, time.Duration) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.calls++; s.calls%sweepEvery == 0 {
+		for k, b := range s.buckets {
+			if now.After(b.full) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rps)
+	b.last = now
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rps * float64(time.Second))
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rps * float64(time.Second)))
+	return true, 0
+}
+
+// rateLimitMiddleware answers requests beyond opts with 429 and a Retry-After header through the
+// responder. Buckets are named after the route, and the client when opts.PerClient is set.
+func rateLimitMiddleware(store RateLimitStore, opts RateLimitOptions, route string, clientKey func(*fasthttp.RequestCtx) string, responder Responder) Middleware {
+	burst := opts.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(opts.RPS))
+	}
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			key := route
+			if opts.PerClient {
+				key += " " + clientKey(ctx)
+			}
+
+			if ok, wait := store.Allow(key, opts.RPS, burst); !ok {
+				seconds := int(math.Ceil(wait.Seconds()))
+				ctx.Response.Header.Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+				responder.Error(ctx, fasthttp.StatusTooManyRequests, CodeTooManyRequests, "too many requests", nil)
+				return
+			}
+
+			next(ctx)
+		}
+	}
+}
+
+// remoteIPKey is the default Config.RateLimitKey: the client's IP address.
+func remoteIPKey(ctx *fasthttp.RequestCtx) string {
+	return ctx.RemoteIP().String()
+}
+
+// rateLimitOptions converts a route's rate_limit value, a map with rps, and optionally burst and per_client.
+func rateLimitOptions(val any) (*RateLimitOptions, error) {
+	v, ok := val.(map[string]any)
+	if !ok {
+		return nil, errors.New("route rate_limit must be a map such as {rps: 10, burst: 20}")
+	}
+
+	opts := &RateLimitOptions{}
+	for key, option := range v {
+		switch key {
+		case "rps":
+			switch n := option.(type) {
+			case int:
+				opts.RPS = float64(n)
+			case float64:
+				opts.RPS = n
+			}
+			if opts.RPS <= 0 {
+				return nil, errors.New("route rate_limit rps must be a positive number")
+			}
+		case "burst":
+			n, ok := wholeNumber(option)
+			if !ok || n <= 0 {
+				return nil, errors.New("route rate_limit burst must be a positive whole number")
+			}
+			opts.Burst = n
+		case "per_client":
+			perClient, ok := option.(bool)
+			if !ok {
+				return nil, errors.New("route rate_limit per_client must be true or false")
+			}
+			opts.PerClient = perClient
+		default:
+			return nil, fmt.Errorf("route rate_limit has unknown option %q", key)
+		}
+	}
+	if opts.RPS == 0 {
+		return nil, errors.New("route rate_limit needs rps")
+	}
+	return opts, nil
+}
@@ -0,0 +1,94 @@
+package routek
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := store.Allow("login", 10, 3); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, wait := store.Allow("login", 10, 3)
+	if ok {
+		t.Fatal("request beyond the burst was allowed")
+	}
+	if wait <= 0 || wait > 100*time.Millisecond {
+		t.Errorf("retry after %v, want up to one token interval of 100ms", wait)
+	}
+	if ok, _ := store.Allow("search", 10, 3); !ok {
+		t.Error("an empty bucket limited another key")
+	}
+
+	// At 10 rps one token is back after 100ms, and only one.
+	time.Sleep(120 * time.Millisecond)
+	if ok, _ := store.Allow("login", 10, 3); !ok {
+		t.Fatal("bucket did not refill")
+	}
+	if ok, _ := store.Allow("login", 10, 3); ok {
+		t.Error("bucket refilled faster than rps")
+	}
+}
+
+func TestRateLimitRoute(t *testing.T) {
+	rt := newTestRouter(t, `
+auth:
+  route:
+    - get: /ping
+      handler: Ping
+      rate_limit: {rps: 1, burst: 2, per_client: true}
+`, Config{
+		Handlers:     map[string]any{"auth": probeHandlers{}},
+		RateLimitKey: func(ctx *fasthttp.RequestCtx) string { return string(ctx.Request.Header.Peek("X-Client")) },
+	})
+
+	for i := 0; i < 2; i++ {
+		if status := serve(rt.Handler, fasthttp.MethodGet, "/ping", "X-Client", "a").Response.StatusCode(); status != fasthttp.StatusOK {
+			t.Fatalf("request %d: status %d, want 200 within the burst", i+1, status)
+		}
+	}
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/ping", "X-Client", "a")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusTooManyRequests {
+		t.Fatalf("status %d beyond the burst, want 429", status)
+	}
+	if got := string(ctx.Response.Header.Peek("Retry-After")); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if code := decodeEnvelope(t, ctx.Response.Body())["code"]; code != string(CodeTooManyRequests) {
+		t.Errorf("code %v, want %s through the responder", code, CodeTooManyRequests)
+	}
+
+	if status := serve(rt.Handler, fasthttp.MethodGet, "/ping", "X-Client", "b").Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Errorf("another client: status %d, want its own bucket", status)
+	}
+}
+
+func TestRateLimitOptions(t *testing.T) {
+	opts, err := rateLimitOptions(map[string]any{"rps": 0.5, "burst": float64(3), "per_client": true})
+	if err != nil {
+		t.Fatalf("rateLimitOptions: %v", err)
+	}
+	if *opts != (RateLimitOptions{RPS: 0.5, Burst: 3, PerClient: true}) {
+		t.Errorf("options %+v, want rps 0.5, burst 3 and per_client", *opts)
+	}
+
+	for name, val := range map[string]any{
+		"not a map":           10,
+		"missing rps":         map[string]any{"burst": 5},
+		"zero rps":            map[string]any{"rps": 0},
+		"fractional burst":    map[string]any{"rps": 1, "burst": 1.5},
+		"per_client a string": map[string]any{"rps": 1, "per_client": "yes"},
+		"unknown option":      map[string]any{"rps": 1, "window": "1m"},
+	} {
+		if _, err := rateLimitOptions(val); err == nil {
+			t.Errorf("%s: rateLimitOptions(%v) succeeded, want an error", name, val)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package routek
+
+import (
+	"runtime/debug"
+
+	"github.com/valyala/fasthttp"
+)
+
+// PanicResponse is the error response written for a recovered panic, as chosen by Config.PanicResponse.
+// Zero fields keep the defaults: 500, INTERNAL_ERROR and "internal server error".
+type PanicResponse struct {
+	Status  int
+	Code    Code
+	Message string
+	// Stack adds the panic and the goroutine stack to the response data, as a debug responder does, even
+	// when the responder is not in debug mode. It only applies to responders created by NewResponder or
+	// NewNegotiatingResponder.
+	Stack bool
+}
+
+// debugger is implemented by the built-in responders, which can hand out a debug-mode copy of themselves.
+type debugger interface {
+	debugging() Responder
+}
+
+// panicStackKey is the user value holding the stack of the panic being recovered.
+const panicStackKey = "routek.panic_stack"
+
+// PanicStack returns the stack of the goroutine the panic being recovered was raised on, for use in
+// Config.PanicHook. Handlers of routes with a timeout run on their own goroutine, whose stack this is;
+// runtime/debug.Stack() in the hook would show the goroutine that re-raised the panic instead. A route
+// timeout stores the stack before re-raising the panic, so recovering middleware of such a route can
+// read it too; elsewhere it is nil outside the hook.
+func PanicStack(ctx *fasthttp.RequestCtx) []byte {
+	stack, _ := ctx.UserValue(panicStackKey).([]byte)
+	return stack
+}
+
+// recoverMiddleware answers panics of a group's routes with recoverPanic.
+func recoverMiddleware(group string, decide func(group string, recovered any) PanicResponse, hook func(*fasthttp.RequestCtx, any), responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer func() {
+				if recovered := recover(); recovered != nil {
+					recoverPanic(ctx, group, recovered, decide, hook, responder)
+				}
+			}()
+
+			next(ctx)
+		}
+	}
+}
+
+// recoverPanic answers a recovered panic with the response chosen by decide, or the default one when
+// decide is nil, after calling hook. It must be called from the deferred recover, so the stack is still
+// the panicking one.
+func recoverPanic(ctx *fasthttp.RequestCtx, group string, recovered any, decide func(group string, recovered any) PanicResponse, hook func(*fasthttp.RequestCtx, any), responder Responder) {
+	// A route timeout re-raising a handler's panic has already stored the stack it was raised on.
+	stack, ok := ctx.UserValue(panicStackKey).([]byte)
+	if !ok {
+		stack = debug.Stack()
+		ctx.SetUserValue(panicStackKey, stack)
+	}
+
+	if hook != nil {
+		hook(ctx, recovered)
+	}
+	var resp PanicResponse
+	if decide != nil {
+		resp = decide(group, recovered)
+	}
+	writePanic(ctx, responder, resp, recovered, stack)
+}
+
+// writePanic writes the error response for a recovered panic.
+func writePanic(ctx *fasthttp.RequestCtx, responder Responder, resp PanicResponse, recovered any, stack []byte) {
+	if resp.Status == 0 {
+		resp.Status = fasthttp.StatusInternalServerError
+	}
+	if resp.Code == "" {
+		resp.Code = CodeInternalError
+	}
+	if resp.Message == "" {
+		resp.Message = "internal server error"
+	}
+	if d, ok := responder.(debugger); ok && resp.Stack {
+		responder = d.debugging()
+	}
+
+	responder.Error(ctx, resp.Status, resp.Code, resp.Message, &panicError{value: recovered, stack: stack})
+}
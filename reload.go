@@ -0,0 +1,49 @@
+package routek
+
+import (
+	"sync/atomic"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// ReloadableRouter serves requests through a route table that Reload replaces atomically, for reloads
+// triggered by the application, such as on SIGHUP. fasthttp's router cannot unregister routes, so each
+// reload builds a fresh router and swaps it in; in-flight requests finish on the table they started with.
+type ReloadableRouter struct {
+	current atomic.Pointer[router.Router]
+}
+
+// NewReloadableRouter builds the initial route table from cfg like NewRouter.
+func NewReloadableRouter(cfg Config) (*ReloadableRouter, error) {
+	rt, err := NewRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReloadableRouter{}
+	r.current.Store(rt)
+	return r, nil
+}
+
+// Reload builds a new route table from cfg and swaps it in. When the build fails the error is returned
+// and the previous table keeps serving.
+func (r *ReloadableRouter) Reload(cfg Config) error {
+	rt, err := NewRouter(cfg)
+	if err != nil {
+		return err
+	}
+
+	r.current.Store(rt)
+	return nil
+}
+
+// Router returns the route table currently serving requests.
+func (r *ReloadableRouter) Router() *router.Router {
+	return r.current.Load()
+}
+
+// Handler serves ctx with the current route table. Pass it to the fasthttp server.
+func (r *ReloadableRouter) Handler(ctx *fasthttp.RequestCtx) {
+	r.current.Load().Handler(ctx)
+}
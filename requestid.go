@@ -0,0 +1,70 @@
+package routek
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+)
+
+// RequestIDHeader is the header the request ID is read from and echoed back in.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey is the user value under which the request ID is stored.
+const requestIDKey = "routek.request_id"
+
+// maxRequestIDLength bounds the incoming IDs that are trusted; longer ones are replaced.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware assigns every request an ID, taken from the X-Request-ID header when the client
+// sent a usable one and otherwise generated as a random UUID. The ID is echoed in the X-Request-ID
+// response header and is available to handlers through RequestID. Register it in GlobalMiddleware;
+// responders created with WithRequestID also include it in the envelope.
+func RequestIDMiddleware() Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			id := string(ctx.Request.Header.Peek(RequestIDHeader))
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+
+			ctx.SetUserValue(requestIDKey, id)
+			ctx.Response.Header.Set(RequestIDHeader, id)
+			next(ctx)
+		}
+	}
+}
+
+// RequestID returns the ID assigned by RequestIDMiddleware, or "" when the middleware did not run.
+func RequestID(ctx *fasthttp.RequestCtx) string {
+	id, _ := ctx.UserValue(requestIDKey).(string)
+	return id
+}
+
+// validRequestID reports whether a client-supplied ID is short and made of printable ASCII, so it is
+// safe to echo in headers and logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("routek: generate request ID: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -2,39 +2,328 @@ package routek
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"reflect"
 	"time"
 
 	"github.com/valyala/fasthttp"
 )
 
-type Responder struct {
+// Responder writes success and error envelopes. Implement it to use a response shape other than
+// the default JSON envelope; CreatedResponder, ValidationResponder and NoContentResponder are optional.
+type Responder interface {
+	Success(ctx *fasthttp.RequestCtx, status int, code Code, message string, data any)
+	Error(ctx *fasthttp.RequestCtx, status int, code Code, message string, err error)
+}
+
+// CreatedResponder is implemented by responders that write 201 responses themselves. Others get the
+// Location header and a 201 Success with code CREATED.
+type CreatedResponder interface {
+	// Created sends a 201 success envelope for data with a Location header pointing at the new resource.
+	Created(ctx *fasthttp.RequestCtx, location string, data any)
+}
+
+// ValidationResponder is implemented by responders that write 422 validation responses themselves.
+// Others get a 422 Error with code VALIDATION_FAILED and the fields as a FieldErrors error.
+type ValidationResponder interface {
+	// ValidationError sends a 422 error envelope whose data carries a message per invalid field.
+	ValidationError(ctx *fasthttp.RequestCtx, fields map[string]string)
+}
+
+// NoContentResponder is implemented by responders that write 204 responses themselves. Others get a 204
+// with the body reset.
+type NoContentResponder interface {
+	// NoContent sends a 204 with no body.
+	NoContent(ctx *fasthttp.RequestCtx)
+}
+
+// respondCreated answers a create through responder's Created, or its Success when it has none.
+func respondCreated(responder Responder, ctx *fasthttp.RequestCtx, location string, data any) {
+	if r, ok := responder.(CreatedResponder); ok {
+		r.Created(ctx, location, data)
+		return
+	}
+
+	ctx.Response.Header.Set("Location", location)
+	responder.Success(ctx, fasthttp.StatusCreated, CodeCreated, "success", data)
+}
+
+// respondValidation answers invalid fields through responder's ValidationError, or its Error when it has none.
+func respondValidation(responder Responder, ctx *fasthttp.RequestCtx, fields map[string]string) {
+	if r, ok := responder.(ValidationResponder); ok {
+		r.ValidationError(ctx, fields)
+		return
+	}
+
+	responder.Error(ctx, fasthttp.StatusUnprocessableEntity, CodeValidationFailed, "validation failed", FieldErrors(fields))
+}
+
+// respondNoContent answers with a 204 through responder's NoContent, or directly when it has none.
+func respondNoContent(responder Responder, ctx *fasthttp.RequestCtx) {
+	if r, ok := responder.(NoContentResponder); ok {
+		r.NoContent(ctx)
+		return
+	}
+
+	ctx.Response.ResetBody()
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// responderKey is the user value under which the serving route's responder is stored.
+const responderKey = "routek.responder"
+
+// defaultResponder answers for ResponderFrom outside routes built by routek.
+var defaultResponder = NewResponder(false)
+
+// loggerKey is the user value under which the serving route's Config.Logger is stored.
+const loggerKey = "routek.logger"
+
+// withResponder makes responder available to the middleware and handler it wraps through ResponderFrom,
+// and logger, when set, to the built-in responders' own error logging.
+func withResponder(responder Responder, logger *slog.Logger) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.SetUserValue(responderKey, responder)
+			if logger != nil {
+				ctx.SetUserValue(loggerKey, logger)
+			}
+			next(ctx)
+		}
+	}
+}
+
+// logResponseError logs a failure to write the response through the Config.Logger of the route serving
+// ctx, or the standard logger when there is none.
+func logResponseError(ctx *fasthttp.RequestCtx, msg string, err error) {
+	if logger, ok := ctx.UserValue(loggerKey).(*slog.Logger); ok {
+		logger.Error("routek: "+msg, "error", err)
+		return
+	}
+
+	log.Printf("%s: %v", msg, err)
+}
+
+// ResponderFrom returns the responder of the route serving ctx, so middleware can answer requests in
+// the router's envelope. Outside a route built by routek it returns NewResponder(false).
+func ResponderFrom(ctx *fasthttp.RequestCtx) Responder {
+	if responder, ok := ctx.UserValue(responderKey).(Responder); ok {
+		return responder
+	}
+
+	return defaultResponder
+}
+
+// JSONResponder is the default Responder, writing the Response envelope as JSON.
+type JSONResponder struct {
 	debug bool
+	opts  responderOptions
 }
 
-// NewResponder creates a responder; debug=true will include error details in responses.
-func NewResponder(debug bool) *Responder {
-	return &Responder{debug: debug}
+// ResponderOption configures a responder created by NewResponder or NewNegotiatingResponder.
+type ResponderOption func(*responderOptions)
+
+type responderOptions struct {
+	compress        bool
+	compressMinSize int
+	requestID       bool
+	omitEmptyData   bool
+	etag            bool
+	successOnNil    bool
+}
+
+// WithCompression gzip- or deflate-compresses response bodies of at least minSize bytes
+// when the request's Accept-Encoding allows it. Smaller bodies are sent as-is.
+func WithCompression(minSize int) ResponderOption {
+	return func(o *responderOptions) {
+		o.compress = true
+		o.compressMinSize = minSize
+	}
+}
+
+// WithRequestID adds the ID assigned by RequestIDMiddleware to every envelope as request_id.
+func WithRequestID() ResponderOption {
+	return func(o *responderOptions) {
+		o.requestID = true
+	}
+}
+
+// requestIDStamper is implemented by the built-in responders to report WithRequestID.
+type requestIDStamper interface {
+	stampsRequestID() bool
+}
+
+func (r *JSONResponder) stampsRequestID() bool {
+	return r.opts.requestID
+}
+
+// OmitEmptyData drops the data key from envelopes whose data is nil or an empty slice, map or struct,
+// instead of writing "data": null or "data": {}.
+func OmitEmptyData() ResponderOption {
+	return func(o *responderOptions) {
+		o.omitEmptyData = true
+	}
+}
+
+// WithSuccessOnNilError makes error-only handlers, func(*fasthttp.RequestCtx) error, that return nil
+// without writing a body answer with a success envelope, OK with null data, at the status they set.
+// By default they are answered with 204 No Content, which suits handlers that write nothing.
+func WithSuccessOnNilError() ResponderOption {
+	return func(o *responderOptions) {
+		o.successOnNil = true
+	}
+}
+
+// nilErrorSuccess is implemented by the built-in responders to report WithSuccessOnNilError.
+type nilErrorSuccess interface {
+	successOnNilError() bool
+}
+
+func (r *JSONResponder) successOnNilError() bool {
+	return r.opts.successOnNil
+}
+
+// emptyData reports whether data would serialize as null or an empty collection.
+func emptyData(data any) bool {
+	if data == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(data)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil() || emptyData(v.Elem().Interface())
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	case reflect.Struct:
+		return v.NumField() == 0
+	default:
+		return false
+	}
+}
+
+// envelope returns the value to marshal for resp, without the data key when it is empty and
+// OmitEmptyData is set.
+func (o responderOptions) envelope(resp Response[any]) any {
+	if !o.omitEmptyData || !emptyData(resp.Data) {
+		return resp
+	}
+
+	return struct {
+		Message   string `json:"message"`
+		Code      Code   `json:"code"`
+		Timestamp int64  `json:"timestamp"`
+		RequestID string `json:"request_id,omitempty"`
+	}{resp.Message, resp.Code, resp.Timestamp, resp.RequestID}
+}
+
+// stamp fills in the envelope fields that depend on the request rather than the handler.
+func (o responderOptions) stamp(ctx *fasthttp.RequestCtx, resp *Response[any]) {
+	if o.requestID {
+		resp.RequestID = RequestID(ctx)
+	}
+}
+
+// NewResponder creates a responder; debug=true will include the error string and any stack trace in
+// error responses, which must not be enabled in production.
+func NewResponder(debug bool, opts ...ResponderOption) *JSONResponder {
+	r := &JSONResponder{debug: debug}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+
+	return r
+}
+
+func (r *JSONResponder) debugging() Responder {
+	d := *r
+	d.debug = true
+	return &d
 }
 
 // Success sends a successful Response with the given status, code, message, and payload data.
-func (r *Responder) Success(ctx *fasthttp.RequestCtx, status int, code Code, message string, data any) {
-	resp := Response[any]{
+func (r *JSONResponder) Success(ctx *fasthttp.RequestCtx, status int, code Code, message string, data any) {
+	resp := successResponse(code, message, data)
+	if r.opts.notModified(ctx, status, mimeJSON, resp) {
+		return
+	}
+	r.opts.stamp(ctx, &resp)
+	r.write(ctx, status, r.opts.envelope(resp))
+}
+
+// Error standardizes error responses.
+func (r *JSONResponder) Error(ctx *fasthttp.RequestCtx, status int, code Code, message string, err error) {
+	status, resp := errorResponse(r.debug, status, code, message, err)
+	r.opts.stamp(ctx, &resp)
+	r.write(ctx, status, r.opts.envelope(resp))
+}
+
+// Created sends a 201 Response for data and sets the Location header.
+func (r *JSONResponder) Created(ctx *fasthttp.RequestCtx, location string, data any) {
+	ctx.Response.Header.Set("Location", location)
+	r.Success(ctx, fasthttp.StatusCreated, CodeCreated, "success", data)
+}
+
+// ValidationError sends a 422 Response with code VALIDATION_FAILED and the per-field messages under data.fields.
+func (r *JSONResponder) ValidationError(ctx *fasthttp.RequestCtx, fields map[string]string) {
+	resp := validationResponse(fields)
+	r.opts.stamp(ctx, &resp)
+	r.write(ctx, fasthttp.StatusUnprocessableEntity, resp)
+}
+
+// NoContent sends a 204 with no body.
+func (r *JSONResponder) NoContent(ctx *fasthttp.RequestCtx) {
+	ctx.Response.ResetBody()
+	ctx.SetStatusCode(fasthttp.StatusNoContent)
+}
+
+// successResponse builds the success envelope.
+func successResponse(code Code, message string, data any) Response[any] {
+	return Response[any]{
 		Message:   message,
 		Code:      code,
 		Data:      data,
 		Timestamp: time.Now().UTC().UnixMilli(),
 	}
-	r.write(ctx, status, resp)
 }
 
-// Error standardizes error responses.
-func (r *Responder) Error(ctx *fasthttp.RequestCtx, status int, code Code, message string, err error) {
+// validationResponse builds the validation error envelope. Fields are always present, so the data key is
+// never omitted.
+func validationResponse(fields map[string]string) Response[any] {
+	if fields == nil {
+		fields = map[string]string{}
+	}
+
+	return Response[any]{
+		Message:   "validation failed",
+		Code:      CodeValidationFailed,
+		Data:      map[string]any{"fields": fields},
+		Timestamp: time.Now().UTC().UnixMilli(),
+	}
+}
+
+// stackTracer is implemented by errors carrying a stack trace, such as errk errors and recovered panics.
+type stackTracer interface {
+	Traces() []string
+}
+
+// errorResponse builds the error envelope, filling in defaults for a missing status, code or message.
+// Error details are only included when debug is set: the error string, and the stack trace of the
+// first error in the chain that carries one.
+func errorResponse(debug bool, status int, code Code, message string, err error) (int, Response[any]) {
 	var data any
 
-	if err != nil && r.debug {
-		data = map[string]any{"error": err.Error()}
+	if err != nil && debug {
+		details := map[string]any{"error": err.Error()}
+		var tracer stackTracer
+		if errors.As(err, &tracer) {
+			if stack := tracer.Traces(); len(stack) > 0 {
+				details["stack"] = stack
+			}
+		}
+		data = details
 	}
 
 	if status == 0 {
@@ -47,20 +336,19 @@ func (r *Responder) Error(ctx *fasthttp.RequestCtx, status int, code Code, messa
 		message = "internal server error"
 	}
 
-	resp := Response[any]{
+	return status, Response[any]{
 		Message:   message,
 		Code:      code,
 		Data:      data,
 		Timestamp: time.Now().UTC().UnixMilli(),
 	}
-	r.write(ctx, status, resp)
 }
 
 // write marshals the payload and writes it to the response, with a resilient fallback when marshaling fails.
-func (r *Responder) write(ctx *fasthttp.RequestCtx, status int, payload any) {
+func (r *JSONResponder) write(ctx *fasthttp.RequestCtx, status int, payload any) {
 	body, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("failed to marshal response: %v", err)
+		logResponseError(ctx, "failed to marshal response", err)
 		fallback := Response[any]{
 			Message:   "internal server error",
 			Code:      CodeInternalError,
@@ -69,7 +357,7 @@ func (r *Responder) write(ctx *fasthttp.RequestCtx, status int, payload any) {
 		}
 		fallbackBody, fallbackErr := json.Marshal(fallback)
 		if fallbackErr != nil {
-			log.Printf("failed to marshal fallback response: %v", fallbackErr)
+			logResponseError(ctx, "failed to marshal fallback response", fallbackErr)
 			ctx.Response.Header.Set("Content-Type", "application/json")
 			ctx.SetStatusCode(fasthttp.StatusInternalServerError)
 			ctx.SetBodyString(
@@ -88,5 +376,25 @@ func (r *Responder) write(ctx *fasthttp.RequestCtx, status int, payload any) {
 
 	ctx.Response.Header.Set("Content-Type", "application/json")
 	ctx.SetStatusCode(status)
-	ctx.SetBody(body)
+	r.opts.setBody(ctx, body)
+}
+
+// setBody writes body, compressing it when compression is enabled, the body is large enough and the client accepts it.
+func (o responderOptions) setBody(ctx *fasthttp.RequestCtx, body []byte) {
+	if !o.compress || len(body) < o.compressMinSize {
+		ctx.SetBody(body)
+		return
+	}
+
+	ctx.Response.Header.Add("Vary", "Accept-Encoding")
+	switch {
+	case ctx.Request.Header.HasAcceptEncoding("gzip"):
+		ctx.Response.Header.Set("Content-Encoding", "gzip")
+		ctx.SetBody(fasthttp.AppendGzipBytes(nil, body))
+	case ctx.Request.Header.HasAcceptEncoding("deflate"):
+		ctx.Response.Header.Set("Content-Encoding", "deflate")
+		ctx.SetBody(fasthttp.AppendDeflateBytes(nil, body))
+	default:
+		ctx.SetBody(body)
+	}
 }
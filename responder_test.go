@@ -0,0 +1,111 @@
+package routek
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-konsultin/errk"
+
+	"github.com/valyala/fasthttp"
+)
+
+// respond runs write against a fresh ctx and returns the decoded envelope.
+func respond(t *testing.T, write func(ctx *fasthttp.RequestCtx)) map[string]any {
+	t.Helper()
+
+	var ctx fasthttp.RequestCtx
+	write(&ctx)
+	return decodeEnvelope(t, ctx.Response.Body())
+}
+
+func TestOmitEmptyData(t *testing.T) {
+	tests := []struct {
+		name string
+		data any
+		omit bool
+	}{
+		{"nil", nil, true},
+		{"typed nil pointer", (*struct{ Name string })(nil), true},
+		{"empty slice", []string{}, true},
+		{"empty map", map[string]any{}, true},
+		{"empty struct", struct{}{}, true},
+		{"zero number", 0, false},
+		{"slice", []string{"a"}, false},
+		{"map", map[string]any{"a": 1}, false},
+	}
+
+	omitting := NewResponder(false, OmitEmptyData())
+	plain := NewResponder(false)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := respond(t, func(ctx *fasthttp.RequestCtx) {
+				omitting.Success(ctx, fasthttp.StatusOK, CodeOK, "success", tt.data)
+			})
+			if _, present := envelope["data"]; present == tt.omit {
+				t.Errorf("data present = %v in %v, want %v", present, envelope, !tt.omit)
+			}
+			if envelope["code"] != string(CodeOK) || envelope["message"] != "success" {
+				t.Errorf("envelope %v lost its other fields", envelope)
+			}
+
+			envelope = respond(t, func(ctx *fasthttp.RequestCtx) {
+				plain.Success(ctx, fasthttp.StatusOK, CodeOK, "success", tt.data)
+			})
+			if _, present := envelope["data"]; !present {
+				t.Errorf("data dropped without OmitEmptyData: %v", envelope)
+			}
+		})
+	}
+}
+
+func TestOmitEmptyDataOnErrors(t *testing.T) {
+	responder := NewResponder(false, OmitEmptyData())
+	envelope := respond(t, func(ctx *fasthttp.RequestCtx) {
+		responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "not found", nil)
+	})
+	if _, present := envelope["data"]; present {
+		t.Errorf("error envelope %v keeps a null data key", envelope)
+	}
+}
+
+func TestDebugErrorDetails(t *testing.T) {
+	traced := errk.NewError("ORDER_LOOKUP", "order lookup failed").Trace()
+	tests := []struct {
+		name  string
+		err   error
+		stack bool
+	}{
+		{"plain error", errors.New("connection refused"), false},
+		{"errk trace", traced, true},
+		{"wrapped errk trace", errors.Join(errors.New("handler"), traced), true},
+		{"recovered panic", &panicError{value: "boom", stack: []byte("goroutine 1 [running]:\nmain.main()\n")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envelope := respond(t, func(ctx *fasthttp.RequestCtx) {
+				NewResponder(true).Error(ctx, fasthttp.StatusInternalServerError, CodeInternalError, "", tt.err)
+			})
+			details, ok := envelope["data"].(map[string]any)
+			if !ok {
+				t.Fatalf("data %v, want error details", envelope["data"])
+			}
+			if details["error"] != tt.err.Error() {
+				t.Errorf("error %v, want %q", details["error"], tt.err.Error())
+			}
+			stack, _ := details["stack"].([]any)
+			if (len(stack) > 0) != tt.stack {
+				t.Errorf("stack %v, want present = %v", details["stack"], tt.stack)
+			}
+
+			envelope = respond(t, func(ctx *fasthttp.RequestCtx) {
+				NewResponder(false).Error(ctx, fasthttp.StatusInternalServerError, CodeInternalError, "", tt.err)
+			})
+			if data, present := envelope["data"]; !present || data != nil {
+				t.Errorf("non-debug data %v, want null", data)
+			}
+			if envelope["message"] != "internal server error" {
+				t.Errorf("non-debug message %v, want the default", envelope["message"])
+			}
+		})
+	}
+}
@@ -5,14 +5,22 @@ type Code string
 
 // Common response codes
 const (
-	CodeOK            Code = "OK"
-	CodeCreated       Code = "CREATED"
-	CodeBadRequest    Code = "BAD_REQUEST"
-	CodeUnauthorized  Code = "UNAUTHORIZED"
-	CodeForbidden     Code = "FORBIDDEN"
-	CodeNotFound      Code = "NOT_FOUND"
-	CodeConflict      Code = "CONFLICT"
-	CodeInternalError Code = "INTERNAL_ERROR"
+	CodeOK                   Code = "OK"
+	CodeCreated              Code = "CREATED"
+	CodeBadRequest           Code = "BAD_REQUEST"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeForbidden            Code = "FORBIDDEN"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeMethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	CodeNotAcceptable        Code = "NOT_ACCEPTABLE"
+	CodeConflict             Code = "CONFLICT"
+	CodePayloadTooLarge      Code = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeValidationFailed     Code = "VALIDATION_FAILED"
+	CodeTooManyRequests      Code = "TOO_MANY_REQUESTS"
+	CodeInternalError        Code = "INTERNAL_ERROR"
+	CodeServiceUnavailable   Code = "SERVICE_UNAVAILABLE"
+	CodeGatewayTimeout       Code = "GATEWAY_TIMEOUT"
 )
 
 // Response is the standard API response structure
@@ -21,4 +29,5 @@ type Response[T any] struct {
 	Code      Code   `json:"code"`
 	Data      T      `json:"data"`
 	Timestamp int64  `json:"timestamp"`
+	RequestID string `json:"request_id,omitempty"`
 }
@@ -1,16 +1,26 @@
 package routek
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"math"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
-	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fasthttp/router"
-	"github.com/go-konsultin/errk"
 	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel/trace"
 	"gopkg.in/yaml.v3"
 )
 
@@ -22,31 +32,357 @@ const (
 type Config struct {
 	// RouteFile is the path to api-route.yaml. If empty, routek searches a few sensible defaults.
 	RouteFile string
-	Handlers  map[string]any
-	Responder *Responder
+	// RouteFiles, when non-empty, is used instead of RouteFile to load several route files merged into one
+	// document. Entries may be glob patterns. Files are merged in sorted order and a group may only be
+	// defined in one file.
+	RouteFiles []string
+	// SearchPaths, when non-empty, replaces the default locations searched for the route file when
+	// RouteFile and RouteFiles are empty. The first existing path is used.
+	SearchPaths []string
+	// FS, when set, is used to locate and read the route file instead of the OS filesystem (e.g. an embed.FS).
+	// Paths are resolved as fs.FS names, so they must be slash-separated and unrooted.
+	FS fs.FS
+	// Loader, when set, reads route files instead of the filesystem, e.g. from a config service.
+	// RouteFile, or each RouteFiles entry, is passed to it as an opaque name: no search paths or globs
+	// are applied and FS is ignored. The name still decides between JSON and YAML as for files.
+	Loader func(name string) ([]byte, error)
+	// Document, when non-nil, supplies the parsed routes directly, e.g. for routes generated in code.
+	// No route file is read: RouteFile, RouteFiles, SearchPaths and FS are ignored.
+	Document Document
+	// MountPath, when set, prefixes every registered path, including static files and the health check,
+	// for serving the router under a sub-path such as /api of a larger application.
+	MountPath string
+	// TenantPrefix, such as /t/:tenant or /t/{tenant}, is placed after MountPath in front of every route
+	// and static path, so each tenant gets the same routes and its value is in ctx.UserValue("tenant").
+	// Route paths must not declare its parameters again. The health check is not tenant-scoped.
+	TenantPrefix string
+	Handlers     map[string]any
+	// DefaultHandlerTarget, when non-nil, is the handler target of groups that have no entry in Handlers,
+	// themselves or through an enclosing group, so generic groups can share one dispatcher.
+	DefaultHandlerTarget any
+	// Middleware is the registry of named middleware that routes reference via their `middleware` key.
+	Middleware map[string]Middleware
+	// Decoders adds or replaces request struct decoders, keyed by media type such as "application/msgpack".
+	// JSON, URL-encoded forms and multipart forms are decoded by default.
+	Decoders map[string]Decoder
+	// Validator, if set, checks each decoded request struct before the handler runs, e.g. NewValidator().
+	Validator Validator
+	// GlobalMiddleware wraps every registered route. The first entry is the outermost and runs first,
+	// followed by the remaining global entries, then the route's own middleware, then the handler.
+	GlobalMiddleware []Middleware
+	// GlobalMiddlewareNames names registry middleware that wraps every route, inside GlobalMiddleware.
+	// A name listed here is not applied again when a group or route also lists it.
+	GlobalMiddlewareNames []string
+	// RecoverPanics turns handler panics into a standard 500 error response instead of crashing the connection.
+	// Route panics are recovered inside the Metrics, AfterResponse and Tracer wrappers, so they see the 500.
+	RecoverPanics bool
+	// PanicHook, if set, is called with the recovered value before the error response is written.
+	// PanicStack(ctx) returns the stack the panic was raised on, including for routes with a timeout,
+	// whose handlers panic on their own goroutine.
+	PanicHook func(ctx *fasthttp.RequestCtx, recovered any)
+	// PanicResponse, if set with RecoverPanics, decides the response to a panic in a route of group,
+	// so internal groups can show the stack while public ones answer a plain 500. Panics outside routes,
+	// such as in NotFoundHandler, are passed an empty group.
+	PanicResponse func(group string, recovered any) PanicResponse
+	// NotFoundHandler, if set, replaces the default JSON 404 response for unmatched paths.
+	NotFoundHandler fasthttp.RequestHandler
+	// MethodNotAllowed answers requests whose path matches but method does not with a 405 and an Allow
+	// header, instead of the default 404.
+	MethodNotAllowed bool
+	// DisableRedirectTrailingSlash stops the router from redirecting /users/ to /users (or the reverse)
+	// when only the other form is registered. DisableRedirectFixedPath stops it from redirecting
+	// case-mismatched or unclean paths such as /Users or /a//users to the registered route.
+	// Both redirects are on by default. They are tried, within the request's method, before the
+	// 405 (MethodNotAllowed) and 404 fallbacks.
+	DisableRedirectTrailingSlash bool
+	DisableRedirectFixedPath     bool
+	// StrictHandlers reports exported handler methods (those accepting a *fasthttp.RequestCtx) that no
+	// route in their group references, so unrouted methods are caught as errors.
+	StrictHandlers bool
+	// StrictFlags makes a route's enabled flag an error when it is malformed or names an unset
+	// environment variable. By default such routes stay enabled and a warning goes to Logger.
+	StrictFlags bool
+	// StrictGroups makes a group that declares no routes, often a sign of misindented YAML, an error.
+	// By default such groups are skipped, without needing a handler target, and a warning goes to Logger.
+	StrictGroups bool
+	// CollectErrors makes NewRouter check every route and return all problems joined with errors.Join,
+	// instead of stopping at the first one.
+	CollectErrors bool
+	// MaxRoutes and MaxPathDepth guard against pathological route files: the build fails once more than
+	// MaxRoutes method and path pairs are declared, or a path has more than MaxPathDepth segments.
+	// Zero means unlimited. Exceeding MaxRoutes stops the build even with CollectErrors.
+	MaxRoutes    int
+	MaxPathDepth int
+	// HealthCheck, when set, registers a GET health endpoint alongside the routes from the route file.
+	HealthCheck *HealthCheckConfig
+	// AfterResponse, if set, is called after every request to a registered route, static files included,
+	// once its response is final: on success, on error, and when the handler wrote the response itself.
+	// Status is the one actually set; duration covers all middleware.
+	AfterResponse func(ctx *fasthttp.RequestCtx, group, handler string, status int, duration time.Duration)
+	// ResponseCache stores the responses of routes declaring `cache`. Defaults to NewLRUCache(DefaultCacheSize),
+	// shared by all routes. CacheRecorder, if set, observes each cache hit and miss.
+	ResponseCache ResponseCache
+	CacheRecorder CacheRecorder
+	// CanarySource, if set, drives the weighted choice between a route's canary handlers, e.g. a seeded
+	// rand.NewPCG for reproducible tests. Defaults to the global math/rand/v2 generator.
+	CanarySource rand.Source
+	// RateLimitStore holds the token buckets of routes declaring `rate_limit`. Defaults to
+	// NewMemoryRateLimitStore(), shared by all routes. RateLimitKey names the client for per_client
+	// limits, e.g. from an X-Forwarded-For header behind a proxy; it defaults to ctx.RemoteIP().
+	RateLimitStore RateLimitStore
+	RateLimitKey   func(ctx *fasthttp.RequestCtx) string
+	// Metrics, if set, observes the method, route pattern, status and duration of every request to a registered route.
+	Metrics MetricsRecorder
+	// Logger, if set, receives each registered route at debug level and each error returned by a
+	// handler at error level. Nothing is logged by default.
+	Logger *slog.Logger
+	// Tracer, if set, runs every request to a registered route inside a span named "group.handler",
+	// continuing the trace context found in the request headers.
+	Tracer trace.Tracer
+	// ErrorMapper translates handler errors into the status, code and message of the error response.
+	// Defaults to reading errk.Error values, and 500/INTERNAL_ERROR for anything else.
+	ErrorMapper func(err error) (status int, code Code, message string)
+	// NoContentOnNilData answers a handler's nil data, including a nil pointer, with 204 No Content
+	// instead of a success envelope with null data, unless the handler chose a status other than 200.
+	NoContentOnNilData bool
+	// Responder writes success and error envelopes. Defaults to NewResponder(false).
+	Responder Responder
+	// Responders overrides Responder for the routes of the groups it names. A nested group uses the entry
+	// of its nearest named ancestor, like handler targets. Unmatched paths and the health check keep
+	// using Responder.
+	Responders map[string]Responder
+	// Schemas maps the names used by a route's request and response fields to Go struct values,
+	// which GenerateOpenAPI describes as component schemas.
+	Schemas map[string]any
 }
 
 type (
-	routeDocument map[string]serviceRoutes
+	// Document is a parsed route file: route groups keyed by name, each served by the Config.Handlers
+	// entry of the same name.
+	Document map[string]Group
 
-	serviceRoutes struct {
-		Routes []yamlRoute `yaml:"route"`
+	// Group is a named set of routes sharing a prefix, middleware and handler target.
+	Group struct {
+		// Prefix is joined to every route path in the group.
+		Prefix string `yaml:"prefix" json:"prefix"`
+		// Middleware names middleware applied to every route in the group, outside the routes' own.
+		Middleware []string `yaml:"middleware" json:"middleware"`
+		Routes     []Route  `yaml:"route" json:"route"`
+		// Static serves directories of files. A group with only static routes needs no handler target.
+		Static []StaticRoute `yaml:"static" json:"static"`
+		// DefaultErrorCode replaces INTERNAL_ERROR for handler errors that are not errk errors.
+		// It is not applied when Config.ErrorMapper is set.
+		DefaultErrorCode Code `yaml:"default_error_code" json:"default_error_code"`
+		// Groups nests subgroups that inherit the prefix, middleware and handler target.
+		Groups map[string]Group `yaml:"groups" json:"groups"`
+
+		// targets lists the Config.Handlers keys to try for a flattened group, nearest first.
+		targets []string
 	}
 
-	yamlRoute struct {
-		Method  string
+	// Route is a single route declaration. Methods, Path and Handler are required.
+	Route struct {
+		// Methods lists the upper-case HTTP methods the route answers, such as "GET" or "PROPFIND".
+		Methods []string
 		Path    string
+		// Aliases lists further paths, relative to the group prefix like Path, that the route also answers
+		// with the same handler and middleware, e.g. an unversioned path kept during a migration. They must
+		// declare the same path parameters as Path.
+		Aliases []string
+		// Handler names the method on the group's handler target.
 		Handler string
+		// Canary, instead of Handler, maps several handler names to positive weights; each request goes
+		// to one of them, chosen at random in proportion to its weight.
+		Canary     map[string]int
+		Middleware []string
+		// Constraints maps path parameter names to "int", "uuid" or "regex:<pattern>".
+		Constraints map[string]string
+		// Query lists the required query parameters, each optionally typed like a constraint: "limit:int".
+		Query []string
+		// Timeout bounds the handler; zero means no timeout.
+		Timeout time.Duration
+		// Cache serves the route's 200 responses from Config.ResponseCache for this long; GET and HEAD only.
+		Cache time.Duration
+		// Deprecated adds a Deprecation header to every response, and Sunset, when set, a Sunset header.
+		Deprecated bool
+		Sunset     time.Time
+		// Enabled is the route's enabled flag, a boolean or a ${VAR} reference; empty means enabled.
+		Enabled string
+		// MaxBody is the declared body size limit, such as "1MB"; empty means no limit.
+		MaxBody string
+		// Consumes lists the accepted request media types, which may be wildcards such as application/*.
+		// Empty accepts any.
+		Consumes []string
+		// Produces lists the response media types the route can send; requests accepting none of them
+		// are answered with 406. Empty skips the check.
+		Produces []string
+		// Log, when set, writes an access log line per request to Config.Logger; see AccessLog.
+		Log *AccessLogOptions
+		// Breaker, when set, guards the route with a CircuitBreaker.
+		Breaker *BreakerOptions
+		// RateLimit, when set, answers requests beyond its rate with 429, using Config.RateLimitStore.
+		RateLimit *RateLimitOptions
+		// Headers are set on every response of the route, success or error, before the handler runs.
+		Headers map[string]string
+		// SuccessCode replaces OK or CREATED as the code of the route's success envelopes.
+		SuccessCode Code
+		// Request and Response name entries of Config.Schemas describing the bodies, for GenerateOpenAPI.
+		Request  string
+		Response string
+		// Summary and Description document the route for GenerateOpenAPI and RegisteredRoute; routing
+		// ignores them.
+		Summary     string
+		Description string
 	}
 )
 
-func (r *yamlRoute) UnmarshalYAML(value *yaml.Node) error {
-	// Decode into a plain map to find the HTTP method key and the handler field.
+// httpMethods lists the HTTP methods that may be used as route keys or in a methods list, keyed by their
+// lower-case YAML spelling. Other methods are declared with the method key.
+var httpMethods = map[string]string{
+	"get":     fasthttp.MethodGet,
+	"post":    fasthttp.MethodPost,
+	"put":     fasthttp.MethodPut,
+	"delete":  fasthttp.MethodDelete,
+	"patch":   fasthttp.MethodPatch,
+	"head":    fasthttp.MethodHead,
+	"options": fasthttp.MethodOptions,
+}
+
+// isMethodToken reports whether method is a valid HTTP token (RFC 9110, section 5.6.2).
+func isMethodToken(method string) bool {
+	if method == "" {
+		return false
+	}
+
+	for i := 0; i < len(method); i++ {
+		c := method[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// looksLikeMethod reports whether an unrecognised route key was probably meant as an HTTP method:
+// it maps to a string and is either a near-miss of a known method name or maps to an absolute path.
+func looksLikeMethod(key string, val any) bool {
+	path, ok := val.(string)
+	if !ok {
+		return false
+	}
+
+	if strings.HasPrefix(path, "/") {
+		return true
+	}
+
+	for name := range httpMethods {
+		// Allow one typo in short names such as get, two in longer ones such as options.
+		if editDistance(key, name) <= max(1, len(name)/3) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr := make([]int, len(b)+1)
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev = curr
+	}
+
+	return prev[len(b)]
+}
+
+// extensionPrefix marks top-level keys that hold shared YAML anchors rather than a route group.
+const extensionPrefix = "x-"
+
+func (d *Document) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]yaml.Node
+	if err := value.Decode(&raw); err != nil {
+		return err
+	}
+
+	doc := make(Document, len(raw))
+	for group, node := range raw {
+		if strings.HasPrefix(group, extensionPrefix) {
+			continue
+		}
+
+		var routes Group
+		if err := node.Decode(&routes); err != nil {
+			return err
+		}
+		doc[group] = routes
+	}
+
+	*d = doc
+	return nil
+}
+
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	doc := make(Document, len(raw))
+	for group, msg := range raw {
+		if strings.HasPrefix(group, extensionPrefix) {
+			continue
+		}
+
+		var routes Group
+		if err := json.Unmarshal(msg, &routes); err != nil {
+			return err
+		}
+		doc[group] = routes
+	}
+
+	*d = doc
+	return nil
+}
+
+func (r *Route) UnmarshalYAML(value *yaml.Node) error {
+	// Decode into a plain map to find the HTTP method keys and the handler field.
 	var raw map[string]any
 	if err := value.Decode(&raw); err != nil {
 		return err
 	}
 
+	return r.fromMap(raw)
+}
+
+func (r *Route) UnmarshalJSON(data []byte) error {
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	return r.fromMap(raw)
+}
+
+// fromMap populates the route from a decoded route entry, shared by the YAML and JSON decoders
+// so both formats accept the same keys and report the same errors.
+func (r *Route) fromMap(raw map[string]any) error {
 	for key, val := range raw {
 		lowerKey := strings.ToLower(key)
 		switch lowerKey {
@@ -54,196 +390,1555 @@ func (r *yamlRoute) UnmarshalYAML(value *yaml.Node) error {
 			if handler, ok := val.(string); ok {
 				r.Handler = handler
 			}
-		case "get", "post", "put", "delete", "patch", "head", "options":
-			r.Method = strings.ToUpper(lowerKey)
+		case "path":
+			path, ok := val.(string)
+			if !ok {
+				return errors.New("route path must be a string")
+			}
+			if err := r.setPath(path); err != nil {
+				return err
+			}
+		case "middleware":
+			names, err := stringList(val)
+			if err != nil {
+				return errors.New("route middleware must be a list of names")
+			}
+			r.Middleware = names
+		case "constraints":
+			constraints, ok := val.(map[string]any)
+			if !ok {
+				return errors.New("route constraints must map parameter names to constraint types")
+			}
+			r.Constraints = make(map[string]string, len(constraints))
+			for name, kind := range constraints {
+				k, ok := kind.(string)
+				if !ok {
+					return fmt.Errorf("route constraint for %q must be a string", name)
+				}
+				r.Constraints[name] = k
+			}
+		case "aliases":
+			paths, err := stringList(val)
+			if err != nil {
+				return errors.New("route aliases must be a list of paths")
+			}
+			r.Aliases = paths
+		case "query":
+			names, err := stringList(val)
+			if err != nil {
+				return errors.New("route query must be a list of parameter names")
+			}
+			r.Query = names
+		case "summary", "description":
+			text, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("route %s must be a string", lowerKey)
+			}
+			if lowerKey == "summary" {
+				r.Summary = text
+			} else {
+				r.Description = text
+			}
+		case "request", "response":
+			name, ok := val.(string)
+			if !ok {
+				return fmt.Errorf("route %s must name a schema", lowerKey)
+			}
+			if lowerKey == "request" {
+				r.Request = name
+			} else {
+				r.Response = name
+			}
+		case "enabled":
+			switch v := val.(type) {
+			case bool:
+				r.Enabled = strconv.FormatBool(v)
+			case string:
+				r.Enabled = v
+			default:
+				return errors.New("route enabled must be a boolean or a ${VAR} reference")
+			}
+		case "deprecated":
+			deprecated, ok := val.(bool)
+			if !ok {
+				return errors.New("route deprecated must be true or false")
+			}
+			r.Deprecated = deprecated
+		case "sunset":
+			switch v := val.(type) {
+			case time.Time:
+				r.Sunset = v
+			case string:
+				sunset, err := parseSunset(v)
+				if err != nil {
+					return err
+				}
+				r.Sunset = sunset
+			default:
+				return errors.New("route sunset must be a date such as 2026-06-30")
+			}
+		case "max_body":
+			if v, ok := val.(string); ok {
+				r.MaxBody = v
+			} else if n, ok := wholeNumber(val); ok {
+				r.MaxBody = strconv.Itoa(n)
+			} else {
+				return errors.New("route max_body must be a size such as 1MB")
+			}
+		case "log":
+			opts, err := accessLogOptions(val)
+			if err != nil {
+				return err
+			}
+			r.Log = opts
+		case "breaker":
+			opts, err := breakerOptions(val)
+			if err != nil {
+				return err
+			}
+			r.Breaker = opts
+		case "rate_limit":
+			opts, err := rateLimitOptions(val)
+			if err != nil {
+				return err
+			}
+			r.RateLimit = opts
+		case "headers":
+			headers, err := routeHeaders(val)
+			if err != nil {
+				return err
+			}
+			r.Headers = headers
+		case "canary":
+			weights, err := canaryWeights(val)
+			if err != nil {
+				return err
+			}
+			r.Canary = weights
+		case "success_code":
+			if v, ok := val.(string); ok {
+				r.SuccessCode = Code(v)
+			} else if n, ok := wholeNumber(val); ok {
+				r.SuccessCode = Code(strconv.Itoa(n))
+			}
+			if r.SuccessCode == "" {
+				return errors.New("route success_code must be a non-empty string or a whole number")
+			}
+		case "consumes", "produces":
+			types, err := stringList(val)
+			if mediaType, ok := val.(string); ok {
+				types, err = []string{mediaType}, nil
+			}
+			if err != nil {
+				return fmt.Errorf("route %s must be a media type or a list of them", lowerKey)
+			}
+			if lowerKey == "consumes" {
+				r.Consumes = types
+			} else {
+				r.Produces = types
+			}
+		case "cache":
+			d, ok := val.(string)
+			if !ok {
+				return errors.New("route cache must be a duration string such as 60s")
+			}
+			ttl, err := time.ParseDuration(d)
+			if err != nil || ttl <= 0 {
+				return fmt.Errorf("route cache %q must be a positive duration such as 60s", d)
+			}
+			r.Cache = ttl
+		case "timeout":
+			d, ok := val.(string)
+			if !ok {
+				return errors.New("route timeout must be a duration string such as 2s")
+			}
+			timeout, err := time.ParseDuration(d)
+			if err != nil || timeout <= 0 {
+				return fmt.Errorf("route timeout %q must be a positive duration such as 2s", d)
+			}
+			r.Timeout = timeout
+		case "method":
+			name, ok := val.(string)
+			if !ok {
+				return errors.New("route method must be a string such as PROPFIND")
+			}
+			method := strings.ToUpper(name)
+			if !isMethodToken(method) {
+				return fmt.Errorf("route method %q is not a valid HTTP method token", name)
+			}
+			if err := r.addMethod(method); err != nil {
+				return err
+			}
+		case "methods":
+			names, err := stringList(val)
+			if err != nil {
+				return errors.New("route methods must be a list of HTTP methods")
+			}
+			for _, name := range names {
+				method, ok := httpMethods[strings.ToLower(name)]
+				if !ok {
+					return fmt.Errorf("unknown HTTP method %q", name)
+				}
+				if err := r.addMethod(method); err != nil {
+					return err
+				}
+			}
+		default:
+			method, ok := httpMethods[lowerKey]
+			if !ok {
+				if looksLikeMethod(lowerKey, val) {
+					return fmt.Errorf("unknown HTTP method %q", key)
+				}
+				continue
+			}
 			path, ok := val.(string)
 			if !ok {
 				return fmt.Errorf("route %q must map to a path string", key)
 			}
-			r.Path = path
+			if err := r.addMethod(method); err != nil {
+				return err
+			}
+			if err := r.setPath(path); err != nil {
+				return err
+			}
 		}
 	}
 
-	if r.Method == "" {
+	if err := r.check(); err != nil {
+		return err
+	}
+
+	// Map iteration order is random; keep the registration order stable.
+	sort.Strings(r.Methods)
+
+	return nil
+}
+
+// check reports a route that lacks a method, path or handler, declares a method that is not an
+// upper-case HTTP token, has an incomplete breaker, or caches a method other than GET and HEAD.
+func (r *Route) check() error {
+	if len(r.Methods) == 0 {
 		return errors.New("route does not declare an HTTP method")
 	}
 
+	for _, method := range r.Methods {
+		if method != strings.ToUpper(method) || !isMethodToken(method) {
+			return fmt.Errorf("HTTP method %q must be an upper-case token such as GET or PROPFIND", method)
+		}
+	}
+
 	if r.Path == "" {
 		return errors.New("route does not declare a path")
 	}
 
-	if r.Handler == "" {
+	if r.Handler == "" && len(r.Canary) == 0 {
 		return errors.New("route does not declare a handler")
 	}
 
-	return nil
-}
+	if r.Handler != "" && len(r.Canary) > 0 {
+		return errors.New("route declares both a handler and canary handlers")
+	}
 
-func NewRouter(cfg Config) (*router.Router, error) {
-	if len(cfg.Handlers) == 0 {
-		return nil, errors.New("routek: handler registry is empty")
+	for name, weight := range r.Canary {
+		if weight <= 0 {
+			return fmt.Errorf("route canary weight of %q must be a positive number", name)
+		}
 	}
 
-	routeFile, err := findRouteFile(cfg.RouteFile)
-	if err != nil {
-		return nil, err
+	if err := checkHeaders(r.Headers); err != nil {
+		return err
 	}
 
-	content, err := os.ReadFile(routeFile)
-	if err != nil {
-		return nil, fmt.Errorf("routek: read %s: %w", routeFile, err)
+	if r.RateLimit != nil && (r.RateLimit.RPS <= 0 || r.RateLimit.Burst < 0) {
+		return errors.New("route rate_limit needs a positive rps and a burst that is not negative")
 	}
 
-	var doc routeDocument
-	if err := yaml.Unmarshal(content, &doc); err != nil {
-		return nil, fmt.Errorf("routek: parse %s: %w", routeFile, err)
+	for _, alias := range r.Aliases {
+		if alias == "" {
+			return errors.New("route alias must not be empty")
+		}
 	}
 
-	if len(doc) == 0 {
-		return nil, fmt.Errorf("routek: no routes defined in %s", routeFile)
+	if r.Breaker != nil && (r.Breaker.Threshold <= 0 || r.Breaker.Cooldown <= 0) {
+		return errors.New("route breaker needs a positive threshold and cooldown")
 	}
 
-	rt := router.New()
-	rt.HandleMethodNotAllowed = false // Return 404 instead of 405 for method mismatches
-	responder := cfg.Responder
-	if responder == nil {
-		responder = NewResponder(false)
+	if r.Cache != 0 {
+		for _, method := range r.Methods {
+			if method != fasthttp.MethodGet && method != fasthttp.MethodHead {
+				return fmt.Errorf("route cache is only allowed on GET and HEAD routes, not %s", method)
+			}
+		}
 	}
 
-	// Custom NotFound handler with JSON response
-	rt.NotFound = func(ctx *fasthttp.RequestCtx) {
-		responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "Not Found", nil)
+	return nil
+}
+
+// accessLogOptions converts a route's log value: true, false, or a map of AccessLogOptions keys.
+func accessLogOptions(val any) (*AccessLogOptions, error) {
+	switch v := val.(type) {
+	case bool:
+		if !v {
+			return nil, nil
+		}
+		return &AccessLogOptions{}, nil
+	case map[string]any:
+		opts := &AccessLogOptions{}
+		for key, option := range v {
+			var ok bool
+			switch key {
+			case "headers":
+				opts.Headers, ok = option.(bool)
+			case "body":
+				opts.Body, ok = option.(bool)
+			case "redact":
+				names, err := stringList(option)
+				opts.Redact, ok = names, err == nil
+			default:
+				return nil, fmt.Errorf("route log has unknown option %q", key)
+			}
+			if !ok {
+				return nil, fmt.Errorf("route log option %q has the wrong type", key)
+			}
+		}
+		return opts, nil
+	default:
+		return nil, errors.New("route log must be true, false or a map of headers, body and redact")
 	}
+}
 
-	for group, routes := range doc {
-		handlerTarget, ok := cfg.Handlers[group]
+// stringList converts a decoded YAML sequence into a slice of strings.
+func stringList(val any) ([]string, error) {
+	items, ok := val.([]any)
+	if !ok {
+		return nil, errors.New("not a list")
+	}
+
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
 		if !ok {
-			return nil, fmt.Errorf("routek: handler target for group %q not provided", group)
+			return nil, errors.New("list item is not a string")
 		}
+		list = append(list, s)
+	}
 
-		if handlerTarget == nil {
-			return nil, fmt.Errorf("routek: handler target for group %q is nil", group)
+	return list, nil
+}
+
+// wholeNumber converts a decoded whole number: an int from YAML, or a float64 without a fraction from
+// JSON, which decodes every number as float64.
+func wholeNumber(val any) (int, bool) {
+	switch n := val.(type) {
+	case int:
+		return n, true
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt && n < math.MaxInt {
+			return int(n), true
 		}
+	}
 
-		for _, r := range routes.Routes {
-			handlerFn, err := buildHandler(handlerTarget, r.Handler, responder)
-			if err != nil {
-				return nil, fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)
-			}
+	return 0, false
+}
 
-			rt.Handle(r.Method, r.Path, handlerFn)
+// addMethod records an HTTP method for the route, rejecting duplicates.
+func (r *Route) addMethod(method string) error {
+	for _, m := range r.Methods {
+		if m == method {
+			return fmt.Errorf("route declares method %s more than once", method)
 		}
 	}
 
-	return rt, nil
+	r.Methods = append(r.Methods, method)
+	return nil
 }
 
-func findRouteFile(path string) (string, error) {
-	if path != "" {
-		if exists(path) {
-			return path, nil
+// setPath records the route path. Several method keys may share a path, but not disagree on it.
+func (r *Route) setPath(path string) error {
+	if r.Path != "" && r.Path != path {
+		return fmt.Errorf("route declares conflicting paths %q and %q", r.Path, path)
+	}
+
+	r.Path = path
+	return nil
+}
+
+// RegisteredRoute describes a route registered on the router.
+// Built-in routes such as the health check have an empty Group and Handler; static file routes only
+// have an empty Handler.
+type RegisteredRoute struct {
+	Method  string
+	Path    string
+	Group   string
+	Handler string
+	// Deprecated reports whether the route is marked deprecated in the route file.
+	Deprecated bool
+	// Summary and Description are the route's documentation fields from the route file.
+	Summary     string
+	Description string
+	// AliasOf is the full primary path for a route registered from the declaration's aliases.
+	AliasOf string
+
+	// route is the declaration the route was built from; nil for built-in routes.
+	route *Route
+}
+
+func NewRouter(cfg Config) (*router.Router, error) {
+	rt, _, err := NewRouterWithRoutes(cfg)
+	return rt, err
+}
+
+// NewRouterWithRoutes builds the router like NewRouter and also returns the registered routes in registration order.
+func NewRouterWithRoutes(cfg Config) (*router.Router, []RegisteredRoute, error) {
+	return newRouter(cfg, func() (Document, error) {
+		return loadRouteDocument(cfg)
+	})
+}
+
+// NewRouterFromBytes builds the router from an in-memory route document instead of a route file.
+// The content may be YAML or JSON; RouteFile, RouteFiles, FS and Document are ignored.
+func NewRouterFromBytes(content []byte, cfg Config) (*router.Router, error) {
+	rt, _, err := newRouter(cfg, func() (Document, error) {
+		doc, err := parseRouteDocument("", content)
+		if err != nil {
+			return nil, fmt.Errorf("routek: parse route document: %w", err)
 		}
 
-		return "", fmt.Errorf("routek: route file %q not found", path)
+		if len(doc) == 0 {
+			return nil, errors.New("routek: no routes defined in route document")
+		}
+
+		return doc, nil
+	})
+
+	return rt, err
+}
+
+// newRouter configures a router and registers the routes of the document returned by load.
+func newRouter(cfg Config, load func() (Document, error)) (*router.Router, []RegisteredRoute, error) {
+	rt := router.New()
+	rt.HandleMethodNotAllowed = cfg.MethodNotAllowed // By default, return 404 instead of 405 for method mismatches
+	rt.RedirectTrailingSlash = !cfg.DisableRedirectTrailingSlash
+	rt.RedirectFixedPath = !cfg.DisableRedirectFixedPath
+	responder := responderFor(cfg)
+
+	// Custom NotFound handler with JSON response
+	rt.NotFound = func(ctx *fasthttp.RequestCtx) {
+		responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "Not Found", nil)
+	}
+	if cfg.NotFoundHandler != nil {
+		rt.NotFound = cfg.NotFoundHandler
 	}
 
-	candidates := []string{
-		DefaultRouteFile,
-		"api-route.yaml",
-		filepath.Join("config", "api-route.yaml"),
+	// The router sets the Allow header before calling this.
+	rt.MethodNotAllowed = func(ctx *fasthttp.RequestCtx) {
+		responder.Error(ctx, fasthttp.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method Not Allowed", nil)
 	}
 
-	for _, candidate := range candidates {
-		if exists(candidate) {
-			return candidate, nil
+	if cfg.RecoverPanics {
+		rt.PanicHandler = func(ctx *fasthttp.RequestCtx, recovered any) {
+			recoverPanic(ctx, "", recovered, cfg.PanicResponse, cfg.PanicHook, responder)
 		}
 	}
 
-	return "", fmt.Errorf("routek: api-route.yaml not found (tried %v)", candidates)
+	routes, err := build(cfg, load, rt, responder, cfg.CollectErrors)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.Logger != nil {
+		for _, route := range routes {
+			cfg.Logger.Debug("routek: registered route", "method", route.Method, "path", route.Path, "group", route.Group, "handler", route.Handler)
+		}
+	}
+
+	return rt, routes, nil
 }
 
-func exists(path string) bool {
-	if path == "" {
-		return false
+// Validate runs the parsing, handler and path checks of NewRouter against a throwaway router, and
+// reports every problem it finds at once, joined with errors.Join.
+// A route file that cannot be read or parsed is reported on its own, since nothing can be checked past it.
+func Validate(cfg Config) error {
+	_, err := build(cfg, func() (Document, error) {
+		return loadRouteDocument(cfg)
+	}, router.New(), responderFor(cfg), true)
+	return err
+}
+
+// MissingHandlers reports, per group, the handler names in the route files that do not resolve on the
+// group's handler target, sorted and without duplicates. Unlike Validate it only checks that the names
+// resolve, not the handler signatures or anything else. The map is empty when every name resolves;
+// the error is only set when the route files cannot be loaded.
+func MissingHandlers(cfg Config) (map[string][]string, error) {
+	doc, err := loadRouteDocument(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	if _, err := os.Stat(path); err == nil {
-		return true
+	doc, err = doc.flatten()
+	if err != nil {
+		return nil, err
 	}
 
-	return false
-}
+	missing := make(map[string][]string)
+	for group, routes := range doc {
+		_, target, _ := routes.target(cfg)
 
-func buildHandler(target any, methodName string, responder *Responder) (fasthttp.RequestHandler, error) {
-	if methodName == "" {
-		return nil, errors.New("handler name is empty")
+		seen := make(map[string]bool)
+		for _, r := range routes.Routes {
+			for _, name := range r.handlerNames() {
+				if seen[name] || hasHandler(target, name) {
+					continue
+				}
+				seen[name] = true
+				missing[group] = append(missing[group], name)
+			}
+		}
+		sort.Strings(missing[group])
 	}
 
-	value := reflect.ValueOf(target)
-	method := value.MethodByName(methodName)
-	if !method.IsValid() {
-		return nil, fmt.Errorf("handler %q not found on %T", methodName, target)
+	return missing, nil
+}
+
+// responderFor returns the configured responder, or the default JSON responder.
+func responderFor(cfg Config) Responder {
+	if cfg.Responder != nil {
+		return cfg.Responder
 	}
 
-	methodType := method.Type()
-	ctxType := reflect.TypeOf(&fasthttp.RequestCtx{})
-	errType := reflect.TypeOf((*error)(nil)).Elem()
+	return NewResponder(false)
+}
+
+// problems accumulates build errors, either stopping at the first one or collecting all of them.
+type problems struct {
+	collect bool
+	errs    []error
+}
+
+// add records err and reports whether the build should stop.
+func (p *problems) add(err error) bool {
+	p.errs = append(p.errs, err)
+	return !p.collect
+}
 
-	if methodType.NumIn() != 1 || methodType.In(0) != ctxType {
-		return nil, fmt.Errorf("handler %q must accept exactly one *fasthttp.RequestCtx argument", methodName)
+func (p *problems) err() error {
+	if len(p.errs) == 1 {
+		return p.errs[0]
 	}
 
-	switch methodType.NumOut() {
-	case 0:
-		return func(ctx *fasthttp.RequestCtx) {
-			method.Call([]reflect.Value{reflect.ValueOf(ctx)})
-		}, nil
-	case 1:
-		if methodType.Out(0) != errType {
-			return nil, fmt.Errorf("handler %q must return either nothing or error", methodName)
+	return errors.Join(p.errs...)
+}
+
+// build binds the routes of the document returned by load and registers them on rt, returning them in
+// registration order.
+func build(cfg Config, load func() (Document, error), rt *router.Router, responder Responder, collect bool) ([]RegisteredRoute, error) {
+	p := &problems{collect: collect}
+
+	if len(cfg.Handlers) == 0 && cfg.DefaultHandlerTarget == nil {
+		if p.add(errors.New("routek: handler registry is empty")) {
+			return nil, p.err()
 		}
+	}
 
-		return func(ctx *fasthttp.RequestCtx) {
-			if res := method.Call([]reflect.Value{reflect.ValueOf(ctx)}); len(res) == 1 && !res[0].IsNil() {
-				err := res[0].Interface().(error)
-				status, code, message := extractErrorInfo(err)
-				responder.Error(ctx, status, code, message, err)
+	for i, mw := range cfg.GlobalMiddleware {
+		if mw == nil {
+			if p.add(fmt.Errorf("routek: global middleware at index %d is nil", i)) {
+				return nil, p.err()
 			}
-		}, nil
-	case 2:
-		if methodType.Out(1) != errType {
-			return nil, fmt.Errorf("handler %q must return (any, error)", methodName)
 		}
+	}
 
-		return func(ctx *fasthttp.RequestCtx) {
-			res := method.Call([]reflect.Value{reflect.ValueOf(ctx)})
-			data := res[0].Interface()
-			if !res[1].IsNil() {
-				err := res[1].Interface().(error)
-				status, code, message := extractErrorInfo(err)
-				responder.Error(ctx, status, code, message, err)
-				return
-			}
+	named, err := resolveMiddleware(cfg.Middleware, uniqueNames(cfg.GlobalMiddlewareNames, nil))
+	if err != nil {
+		if p.add(fmt.Errorf("routek: global %w", err)) {
+			return nil, p.err()
+		}
+	}
+	global := append(cfg.GlobalMiddleware[:len(cfg.GlobalMiddleware):len(cfg.GlobalMiddleware)], named...)
 
-			responder.Success(ctx, fasthttp.StatusOK, CodeOK, "success", data)
-		}, nil
-	default:
-		return nil, fmt.Errorf("handler %q must return either nothing or error", methodName)
+	doc, err := load()
+	if err != nil {
+		p.add(err)
+		return nil, p.err()
 	}
-}
 
-// extractErrorInfo extracts HTTP status, code, and message from errk.Error.
-// Returns defaults if not an errk.Error.
-func extractErrorInfo(err error) (int, Code, string) {
-	var errkErr *errk.Error
-	if errors.As(err, &errkErr) {
-		status := fasthttp.StatusInternalServerError
-		if s, ok := errkErr.Metadata()["http_status"].(int); ok {
-			status = s
+	doc, err = doc.flatten()
+	if err != nil {
+		p.add(err)
+		return nil, p.err()
+	}
+
+	// Iterate groups in a stable order so registration and error reporting are deterministic.
+	groups := make([]string, 0, len(doc))
+	for group := range doc {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	// The default cache is only created when a route declares one.
+	cache := cfg.ResponseCache
+	// The default rate limit store is only created when a route declares a limit.
+	limits := cfg.RateLimitStore
+	clientKey := cfg.RateLimitKey
+	if clientKey == nil {
+		clientKey = remoteIPKey
+	}
+	// pick is shared by all canary routes, so a seeded CanarySource gives one reproducible sequence.
+	var pick func(n int) int
+
+	b := &binding{responder: responder, mapError: cfg.ErrorMapper, logger: cfg.Logger, decoders: decodersFor(cfg), noContentOnNil: cfg.NoContentOnNilData, validator: cfg.Validator}
+
+	// registered maps "METHOD path" to the group that first declared it.
+	registered := make(map[string]string)
+	var routeList []RegisteredRoute
+
+	// referenced records, per Config.Handlers key, the handler names routes resolved against it.
+	referenced := make(map[string]map[string]bool)
+
+	if err := checkResponders(cfg.Responders, doc); err != nil {
+		if p.add(err) {
+			return nil, p.err()
+		}
+	}
+
+	if cfg.TenantPrefix != "" {
+		if err := checkTenantPrefix(cfg.TenantPrefix); err != nil {
+			p.add(err)
+			return nil, p.err()
 		}
-		code := Code(errkErr.Code())
-		message := errkErr.Message()
-		return status, code, message
 	}
-	return fasthttp.StatusInternalServerError, CodeInternalError, "internal server error"
+
+	for _, group := range groups {
+		routes := doc[group]
+		// Everything in the group answers through its own responder when Config.Responders has one.
+		responder := routes.responder(cfg.Responders, responder)
+		if len(routes.Routes) == 0 && len(routes.Static) == 0 {
+			if cfg.StrictGroups {
+				if p.add(fmt.Errorf("routek: group %q declares no routes", group)) {
+					return nil, p.err()
+				}
+			} else if cfg.Logger != nil {
+				cfg.Logger.Warn("routek: group declares no routes, skipping it", "group", group)
+			}
+			continue
+		}
+		if len(routes.Static) > 0 {
+			static, stop := registerStatic(cfg, group, routes, rt, responder, global, registered, p)
+			if stop {
+				return nil, p.err()
+			}
+			routeList = append(routeList, static...)
+			if len(routes.Routes) == 0 {
+				continue
+			}
+		}
+
+		targetName, handlerTarget, ok := routes.target(cfg)
+		if !ok {
+			if p.add(fmt.Errorf("routek: handler target for group %q not provided", group)) {
+				return nil, p.err()
+			}
+			continue
+		}
+
+		if handlerTarget == nil {
+			if p.add(fmt.Errorf("routek: handler target for group %q is nil", targetName)) {
+				return nil, p.err()
+			}
+			continue
+		}
+
+		if err := checkTargetMatches(group, handlerTarget, routes.Routes); err != nil {
+			if p.add(err) {
+				return nil, p.err()
+			}
+			continue
+		}
+
+		prefix, err := expandEnv(routes.Prefix)
+		if err != nil {
+			if p.add(fmt.Errorf("routek: group %q prefix: %w", group, err)) {
+				return nil, p.err()
+			}
+			continue
+		}
+		groupPrefix := prefix
+		prefix = joinPath(basePrefix(cfg), prefix)
+
+		gb := *b
+		gb.responder = responder
+		gb.defaultCode = routes.DefaultErrorCode
+
+		if referenced[targetName] == nil {
+			referenced[targetName] = make(map[string]bool)
+		}
+		for _, r := range routes.Routes {
+			failed := len(p.errs)
+			names := r.handlerNames()
+			for _, name := range names {
+				referenced[targetName][name] = true
+			}
+			// Errors, logs and the route list name canary routes by all their handlers.
+			r.Handler = r.handlerLabel()
+
+			if r.Enabled != "" {
+				enabled, err := routeEnabled(r.Enabled)
+				if err != nil {
+					if cfg.StrictFlags {
+						if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+							return nil, p.err()
+						}
+						continue
+					}
+					if cfg.Logger != nil {
+						cfg.Logger.Warn("routek: route enabled flag ignored, registering the route", "route", group+"."+r.Handler, "error", err)
+					}
+				} else if !enabled {
+					continue
+				}
+			}
+
+			routePath, err := expandEnv(r.Path)
+			if err != nil {
+				if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+					return nil, p.err()
+				}
+				continue
+			}
+			if name, ok := tenantCollision(cfg, joinPath(groupPrefix, routePath)); ok {
+				if p.add(fmt.Errorf("routek: %s.%s: path parameter %q is already declared by Config.TenantPrefix", group, r.Handler, name)) {
+					return nil, p.err()
+				}
+				continue
+			}
+			path := catchAll(joinPath(prefix, routePath))
+			paths := []string{path}
+			for _, alias := range r.Aliases {
+				aliasPath, err := expandEnv(alias)
+				if err == nil {
+					aliasPath = catchAll(joinPath(prefix, aliasPath))
+					if !maps.Equal(pathParams(aliasPath), pathParams(path)) {
+						err = fmt.Errorf("alias %q must declare the same path parameters as %q", aliasPath, path)
+					}
+				}
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+					continue
+				}
+				paths = append(paths, aliasPath)
+			}
+
+			keys := make([]string, 0, 2*len(r.Methods)*len(paths))
+			for _, full := range paths {
+				base, optional, err := optionalBase(full)
+				if err == nil {
+					err = checkPathDepth(cfg, full)
+				}
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+					continue
+				}
+
+				for _, method := range r.Methods {
+					if !optional {
+						keys = append(keys, method+" "+full)
+						continue
+					}
+					// An optional parameter claims the path with the parameter required and the path without it.
+					keys = append(keys, method+" "+strings.TrimSuffix(full, "?}")+"}", method+" "+base)
+				}
+			}
+			if len(p.errs) > failed {
+				continue
+			}
+
+			rb := gb
+			rb.successCode = r.SuccessCode
+			var handlerFn fasthttp.RequestHandler
+			if len(r.Canary) == 0 {
+				handlerFn, err = buildHandler(handlerTarget, r.Handler, &rb)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			} else {
+				handlers, weights := make([]fasthttp.RequestHandler, len(names)), make([]int, len(names))
+				for i, name := range names {
+					handlers[i], err = buildHandler(handlerTarget, name, &rb)
+					if err != nil {
+						if p.add(fmt.Errorf("routek: %s.%s: %w", group, name, err)) {
+							return nil, p.err()
+						}
+					}
+					weights[i] = r.Canary[name]
+				}
+				if pick == nil {
+					pick = canaryPicker(cfg.CanarySource)
+				}
+				handlerFn = canaryHandler(handlers, weights, pick)
+			}
+
+			var validate Middleware
+			if len(r.Constraints) > 0 {
+				validate, err = constraintMiddleware(path, r.Constraints, responder)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			}
+
+			var query []queryParam
+			if len(r.Query) > 0 {
+				query, err = parseQueryParams(r.Query)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			}
+
+			var maxBody int64
+			if r.MaxBody != "" {
+				maxBody, err = parseSize(r.MaxBody)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			}
+
+			var consumes []string
+			if len(r.Consumes) > 0 {
+				consumes, err = parseMediaRanges("consumes", r.Consumes)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			}
+
+			if r.Log != nil && cfg.Logger == nil {
+				if p.add(fmt.Errorf("routek: %s.%s: route log needs Config.Logger", group, r.Handler)) {
+					return nil, p.err()
+				}
+			}
+
+			var produces []string
+			if len(r.Produces) > 0 {
+				produces, err = parseProduces(r.Produces)
+				if err != nil {
+					if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+						return nil, p.err()
+					}
+				}
+			}
+
+			mwNames := append(routes.Middleware[:len(routes.Middleware):len(routes.Middleware)], r.Middleware...)
+			middleware, err := resolveMiddleware(cfg.Middleware, uniqueNames(mwNames, cfg.GlobalMiddlewareNames))
+			if err != nil {
+				if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+					return nil, p.err()
+				}
+			}
+
+			for _, key := range keys {
+				if err := checkRouteCount(cfg, registered, key); err != nil {
+					p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err))
+					return nil, p.err()
+				}
+				if owner, dup := registered[key]; dup {
+					if owner == group {
+						err = fmt.Errorf("routek: duplicate route %s defined twice in group %q", key, group)
+					} else {
+						err = fmt.Errorf("routek: duplicate route %s defined in groups %q and %q", key, owner, group)
+					}
+					if p.add(err) {
+						return nil, p.err()
+					}
+					continue
+				}
+				registered[key] = group
+			}
+
+			// Only register routes that bound cleanly; in collect mode the remaining routes are still checked.
+			if len(p.errs) > failed {
+				continue
+			}
+
+			if r.Timeout > 0 {
+				handlerFn = timeoutMiddleware(r.Timeout, responder)(handlerFn)
+			}
+			if r.Cache > 0 {
+				if cache == nil {
+					cache = NewLRUCache(DefaultCacheSize)
+				}
+				handlerFn = cacheMiddleware(cache, r.Cache, cfg.CacheRecorder, path, responder)(handlerFn)
+			}
+			if r.Breaker != nil {
+				handlerFn = CircuitBreaker(*r.Breaker)(handlerFn)
+			}
+			if len(query) > 0 {
+				handlerFn = queryMiddleware(query, responder)(handlerFn)
+			}
+			if validate != nil {
+				handlerFn = validate(handlerFn)
+			}
+			if len(produces) > 0 {
+				handlerFn = producesMiddleware(produces, responder)(handlerFn)
+			}
+			if len(consumes) > 0 {
+				handlerFn = consumesMiddleware(consumes, responder)(handlerFn)
+			}
+			if maxBody > 0 {
+				handlerFn = bodyLimitMiddleware(maxBody, responder)(handlerFn)
+			}
+			if r.RateLimit != nil {
+				if limits == nil {
+					limits = NewMemoryRateLimitStore()
+				}
+				handlerFn = rateLimitMiddleware(limits, *r.RateLimit, strings.Join(r.Methods, ",")+" "+path, clientKey, responder)(handlerFn)
+			}
+			handlerFn = chain(chain(handlerFn, middleware...), global...)
+			if r.Deprecated || !r.Sunset.IsZero() {
+				handlerFn = deprecationMiddleware(r.Deprecated, r.Sunset)(handlerFn)
+			}
+			if len(r.Headers) > 0 {
+				handlerFn = headersMiddleware(r.Headers)(handlerFn)
+			}
+			if cfg.RecoverPanics {
+				handlerFn = recoverMiddleware(group, cfg.PanicResponse, cfg.PanicHook, responder)(handlerFn)
+			}
+			// Outside recover, so requests that panicked are logged with their 500.
+			if r.Log != nil {
+				handlerFn = AccessLog(cfg.Logger.With("route", group+"."+r.Handler), *r.Log)(handlerFn)
+			}
+			handlerFn = withResponder(responder, cfg.Logger)(handlerFn)
+
+			for _, full := range paths {
+				aliasOf := ""
+				if full != path {
+					aliasOf = path
+				}
+				for _, method := range r.Methods {
+					handle := observe(cfg, handlerFn, group, r.Handler, method, full)
+					if err := register(rt, method, full, handle); err != nil {
+						if p.add(fmt.Errorf("routek: %s.%s: %w", group, r.Handler, err)) {
+							return nil, p.err()
+						}
+						continue
+					}
+					routeList = append(routeList, RegisteredRoute{Method: method, Path: full, Group: group, Handler: r.Handler, Deprecated: r.Deprecated, Summary: r.Summary, Description: r.Description, AliasOf: aliasOf, route: &r})
+				}
+			}
+		}
+
+	}
+
+	if cfg.StrictHandlers {
+		targets := make([]string, 0, len(referenced))
+		for name := range referenced {
+			targets = append(targets, name)
+		}
+		sort.Strings(targets)
+
+		for _, target := range targets {
+			handlers, label := cfg.Handlers[target], target
+			if target == "" {
+				handlers, label = cfg.DefaultHandlerTarget, "DefaultHandlerTarget"
+			}
+			for _, name := range handlerMethods(handlers) {
+				if !referenced[target][name] {
+					if p.add(fmt.Errorf("routek: %s.%s: handler method is not referenced by any route", label, name)) {
+						return nil, p.err()
+					}
+				}
+			}
+		}
+	}
+
+	if cfg.HealthCheck != nil {
+		path := joinPath(cfg.MountPath, cfg.HealthCheck.path())
+		key := fasthttp.MethodGet + " " + path
+		if owner, dup := registered[key]; dup {
+			p.add(fmt.Errorf("routek: health check %s collides with a route in group %q", key, owner))
+		} else if err := register(rt, fasthttp.MethodGet, path, cfg.HealthCheck.handler(responder)); err != nil {
+			p.add(fmt.Errorf("routek: health check: %w", err))
+		} else {
+			routeList = append(routeList, RegisteredRoute{Method: fasthttp.MethodGet, Path: path})
+		}
+	}
+
+	if len(p.errs) > 0 {
+		return nil, p.err()
+	}
+
+	// OPTIONS requests for paths without an OPTIONS route are answered by the router itself; run them
+	// through the global middleware so it can handle CORS preflights.
+	if len(global) > 0 {
+		rt.GlobalOPTIONS = withResponder(responder, cfg.Logger)(chain(func(*fasthttp.RequestCtx) {}, global...))
+	}
+
+	return routeList, nil
+}
+
+// observe wraps a route's handler for method and path in the wrappers that see its final response: the
+// Tracer span, then Metrics, then AfterResponse as the outermost. handler is empty for static routes.
+func observe(cfg Config, handle fasthttp.RequestHandler, group, handler, method, path string) fasthttp.RequestHandler {
+	if cfg.Tracer != nil {
+		name := group
+		if handler != "" {
+			name += "." + handler
+		}
+		handle = tracingMiddleware(cfg.Tracer, name, method, path)(handle)
+	}
+	if cfg.Metrics != nil {
+		handle = metricsMiddleware(cfg.Metrics, method, path)(handle)
+	}
+	if cfg.AfterResponse != nil {
+		handle = afterResponseMiddleware(cfg.AfterResponse, group, handler)(handle)
+	}
+
+	return handle
+}
+
+// flatten resolves nested groups into top-level groups named "parent.child", joining prefixes and
+// middleware from the outermost group inwards. Groups that only hold subgroups are dropped.
+func (d Document) flatten() (Document, error) {
+	flat := make(Document, len(d))
+
+	var walk func(name string, group Group) error
+	walk = func(name string, group Group) error {
+		if len(group.Routes) > 0 || len(group.Static) > 0 || len(group.Groups) == 0 {
+			if _, dup := flat[name]; dup {
+				return fmt.Errorf("routek: group %q is declared both nested and at the top level", name)
+			}
+			flat[name] = Group{
+				Prefix:           group.Prefix,
+				Middleware:       group.Middleware,
+				Routes:           group.Routes,
+				Static:           group.Static,
+				DefaultErrorCode: group.DefaultErrorCode,
+				targets:          append([]string{name}, group.targets...),
+			}
+		}
+
+		for childName, child := range group.Groups {
+			child.Prefix = joinPath(group.Prefix, child.Prefix)
+			child.Middleware = append(group.Middleware[:len(group.Middleware):len(group.Middleware)], child.Middleware...)
+			child.targets = append([]string{name}, group.targets...)
+			if child.DefaultErrorCode == "" {
+				child.DefaultErrorCode = group.DefaultErrorCode
+			}
+			if err := walk(name+"."+childName, child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// Walk in a stable order so a name clash is always reported the same way.
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := walk(name, d[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return flat, nil
+}
+
+// check runs Route.check over every route of a document built in code, which skipped the checks
+// done while decoding a route file.
+func (d Document) check() error {
+	var walk func(name string, group Group) error
+	walk = func(name string, group Group) error {
+		for i := range group.Routes {
+			if err := group.Routes[i].check(); err != nil {
+				return fmt.Errorf("group %q route %d: %w", name, i, err)
+			}
+		}
+
+		for childName, child := range group.Groups {
+			if err := walk(name+"."+childName, child); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	for name, group := range d {
+		if err := walk(name, group); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// target returns the handler target of a flattened group: its own entry in cfg.Handlers, or else the
+// nearest enclosing group's, or else cfg.DefaultHandlerTarget, which is named "".
+func (g Group) target(cfg Config) (string, any, bool) {
+	for _, name := range g.targets {
+		if target, ok := cfg.Handlers[name]; ok {
+			return name, target, true
+		}
+	}
+
+	if cfg.DefaultHandlerTarget != nil {
+		return "", cfg.DefaultHandlerTarget, true
+	}
+	return "", nil, false
+}
+
+// checkTargetMatches reports a handler target that resolves none of the group's handler names, which
+// usually means the wrong value was registered, as one error instead of one per route.
+func checkTargetMatches(group string, target any, routes []Route) error {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range routes {
+		for _, name := range r.handlerNames() {
+			if name == "" || seen[name] {
+				continue
+			}
+			if hasHandler(target, name) {
+				return nil
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+	return fmt.Errorf("routek: handler target for group %q is a %T with none of the group's handlers (%s); check that the right value is registered", group, target, strings.Join(names, ", "))
+}
+
+// responder returns the Config.Responders entry of the group's nearest named target, or fallback.
+func (g Group) responder(responders map[string]Responder, fallback Responder) Responder {
+	for _, name := range g.targets {
+		if responder, ok := responders[name]; ok {
+			return responder
+		}
+	}
+
+	return fallback
+}
+
+// checkResponders reports Config.Responders entries that are nil or name no group of the flattened doc.
+func checkResponders(responders map[string]Responder, doc Document) error {
+	names := make(map[string]bool)
+	for _, group := range doc {
+		for _, name := range group.targets {
+			names[name] = true
+		}
+	}
+
+	keys := make([]string, 0, len(responders))
+	for key := range responders {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if responders[key] == nil {
+			return fmt.Errorf("routek: responder for group %q is nil", key)
+		}
+		if !names[key] {
+			return fmt.Errorf("routek: responder for group %q does not match a group", key)
+		}
+	}
+
+	return nil
+}
+
+// loadRouteDocument returns cfg.Document when set, and otherwise reads and merges the route files
+// selected by cfg.
+func loadRouteDocument(cfg Config) (Document, error) {
+	if cfg.Document != nil {
+		if len(cfg.Document) == 0 {
+			return nil, errors.New("routek: no routes defined in Config.Document")
+		}
+
+		if err := cfg.Document.check(); err != nil {
+			return nil, fmt.Errorf("routek: Config.Document: %w", err)
+		}
+
+		return cfg.Document, nil
+	}
+
+	files, err := routeFiles(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := make(Document)
+	source := make(map[string]string)
+	for _, file := range files {
+		if cfg.Logger != nil {
+			cfg.Logger.Debug("routek: loading route file", "path", file)
+		}
+
+		var content []byte
+		if cfg.Loader != nil {
+			content, err = cfg.Loader(file)
+		} else {
+			content, err = readFile(cfg.FS, file)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("routek: read %s: %w", file, err)
+		}
+
+		part, err := parseRouteDocument(file, content)
+		if err != nil {
+			return nil, fmt.Errorf("routek: parse %s: %w", file, err)
+		}
+
+		for group, routes := range part {
+			if prev, dup := source[group]; dup {
+				return nil, fmt.Errorf("routek: group %q defined in both %s and %s", group, prev, file)
+			}
+			source[group] = file
+			doc[group] = routes
+		}
+	}
+
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("routek: no routes defined in %s", strings.Join(files, ", "))
+	}
+
+	return doc, nil
+}
+
+// routeFiles resolves the files to load: the names given to Config.Loader as they are, the expanded
+// and sorted Config.RouteFiles when set, otherwise the single file found by findRouteFile.
+func routeFiles(cfg Config) ([]string, error) {
+	if cfg.Loader != nil {
+		if len(cfg.RouteFiles) > 0 {
+			return cfg.RouteFiles, nil
+		}
+		if cfg.RouteFile == "" {
+			return nil, errors.New("routek: Config.Loader needs RouteFile or RouteFiles to name what to load")
+		}
+
+		return []string{cfg.RouteFile}, nil
+	}
+
+	if len(cfg.RouteFiles) == 0 {
+		routeFile, err := findRouteFile(cfg.FS, cfg.RouteFile, cfg.SearchPaths)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{routeFile}, nil
+	}
+
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range cfg.RouteFiles {
+		matches, err := glob(cfg.FS, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("routek: route files %q: %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("routek: route file %q not found", pattern)
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// parseRouteDocument decodes content as JSON or YAML, as decided by isJSON.
+func parseRouteDocument(name string, content []byte) (Document, error) {
+	var doc Document
+	if isJSON(name, content) {
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+
+		return doc, nil
+	}
+
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// routeEnabled evaluates an enabled flag after substituting ${VAR} references.
+func routeEnabled(flag string) (bool, error) {
+	value, err := expandEnv(flag)
+	if err != nil {
+		return false, fmt.Errorf("enabled flag %q references an unset environment variable", flag)
+	}
+
+	enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return false, fmt.Errorf("enabled flag %q is not a boolean", value)
+	}
+
+	return enabled, nil
+}
+
+// envPattern matches ${NAME} references in route paths.
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${NAME} references in path with the value of the environment variable,
+// failing when a referenced variable is not set.
+func expandEnv(path string) (string, error) {
+	var missing string
+	expanded := envPattern.ReplaceAllStringFunc(path, func(ref string) string {
+		name := envPattern.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && missing == "" {
+			missing = name
+		}
+		return value
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("path %q: environment variable %q is not set", path, missing)
+	}
+
+	return expanded, nil
+}
+
+// isJSON reports whether a route document is JSON: by extension for .json, .yaml and .yml names,
+// otherwise by whether the content starts with an object.
+func isJSON(name string, content []byte) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return true
+	case ".yaml", ".yml":
+		return false
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte("{"))
+}
+
+// joinPath joins a group prefix and a route path with exactly one slash between them.
+// Without a prefix the path is returned unchanged.
+func joinPath(prefix, path string) string {
+	prefix = strings.TrimRight(prefix, "/")
+	if prefix == "" {
+		return path
+	}
+
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	path = strings.TrimLeft(path, "/")
+	if path == "" {
+		return prefix
+	}
+
+	return prefix + "/" + path
+}
+
+// catchAll rewrites a trailing *name segment into the router's {name:*} catch-all form, so
+// /static/*filepath matches everything under /static/.
+func catchAll(path string) string {
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 || len(path) < i+3 || path[i+1] != '*' {
+		return path
+	}
+
+	return path[:i+1] + "{" + path[i+2:] + ":*}"
+}
+
+// optionalBase returns path without its trailing {name?} segment, and whether path ends in one. The
+// router serves both forms from the one registration; an optional parameter anywhere else is an error.
+func optionalBase(path string) (string, bool, error) {
+	i := strings.LastIndexByte(path, '/')
+	last := path[i+1:]
+	if strings.Contains(path[:i+1], "?}") {
+		return "", false, fmt.Errorf("path %q: optional parameter must be the last path segment", path)
+	}
+	if !strings.HasPrefix(last, "{") || !strings.HasSuffix(last, "?}") {
+		return path, false, nil
+	}
+
+	if i == 0 {
+		return "/", true, nil
+	}
+
+	return path[:i], true, nil
+}
+
+// checkPathDepth enforces Config.MaxPathDepth on a full route path.
+func checkPathDepth(cfg Config, path string) error {
+	if cfg.MaxPathDepth <= 0 {
+		return nil
+	}
+
+	if depth := len(strings.FieldsFunc(path, func(r rune) bool { return r == '/' })); depth > cfg.MaxPathDepth {
+		return fmt.Errorf("path %q has %d segments, more than MaxPathDepth %d", path, depth, cfg.MaxPathDepth)
+	}
+
+	return nil
+}
+
+// checkRouteCount enforces Config.MaxRoutes before key is added to the registered routes.
+func checkRouteCount(cfg Config, registered map[string]string, key string) error {
+	if _, dup := registered[key]; dup || cfg.MaxRoutes <= 0 || len(registered) < cfg.MaxRoutes {
+		return nil
+	}
+
+	return fmt.Errorf("route %s exceeds MaxRoutes %d", key, cfg.MaxRoutes)
+}
+
+// panicError is the error a recovered panic is answered with. Its stack is only shown by debug responders.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// Traces returns the stack of the panicking goroutine, one line per entry.
+func (e *panicError) Traces() []string {
+	return strings.Split(strings.TrimSpace(string(e.stack)), "\n")
+}
+
+// register adds the route to rt, turning the router's panics on invalid or conflicting paths into errors.
+func register(rt *router.Router, method, path string, handler fasthttp.RequestHandler) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("register %s %s: %v", method, path, recovered)
+		}
+	}()
+
+	rt.Handle(method, path, handler)
+	return nil
+}
+
+// uniqueNames returns names without duplicates, keeping the first occurrence, and without the names
+// in exclude, which are already applied further out.
+func uniqueNames(names, exclude []string) []string {
+	seen := make(map[string]bool, len(names)+len(exclude))
+	for _, name := range exclude {
+		seen[name] = true
+	}
+
+	unique := make([]string, 0, len(names))
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
+
+// resolveMiddleware looks up the named middleware in the registry, preserving declaration order.
+func resolveMiddleware(registry map[string]Middleware, names []string) ([]Middleware, error) {
+	middleware := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		mw, ok := registry[name]
+		if !ok || mw == nil {
+			return nil, fmt.Errorf("middleware %q not registered", name)
+		}
+		middleware = append(middleware, mw)
+	}
+
+	return middleware, nil
+}
+
+// ResolveRouteFile returns the route file NewRouter loads when RouteFiles is empty: RouteFile if it
+// exists, otherwise the first existing entry of SearchPaths or the default candidates. Relative paths
+// are resolved against cfg.FS, or the working directory when it is nil.
+func ResolveRouteFile(cfg Config) (string, error) {
+	return findRouteFile(cfg.FS, cfg.RouteFile, cfg.SearchPaths)
+}
+
+// defaultSearchPaths are the locations tried for the route file when neither RouteFile nor SearchPaths is set.
+var defaultSearchPaths = []string{
+	DefaultRouteFile,
+	"api-route.yaml",
+	"config/api-route.yaml",
+}
+
+func findRouteFile(fsys fs.FS, path string, searchPaths []string) (string, error) {
+	if path != "" {
+		if exists(fsys, path) {
+			return path, nil
+		}
+
+		return "", fmt.Errorf("routek: route file %q not found", path)
+	}
+
+	candidates, name := searchPaths, "route file"
+	if len(candidates) == 0 {
+		candidates, name = defaultSearchPaths, "api-route.yaml"
+	}
+
+	for _, candidate := range candidates {
+		if exists(fsys, candidate) {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("routek: %s not found (tried %v)", name, candidates)
+}
+
+// exists reports whether path exists in fsys, or on the OS filesystem when fsys is nil.
+func exists(fsys fs.FS, path string) bool {
+	if path == "" {
+		return false
+	}
+
+	var err error
+	if fsys != nil {
+		_, err = fs.Stat(fsys, path)
+	} else {
+		_, err = os.Stat(path)
+	}
+
+	return err == nil
+}
+
+// glob expands pattern against fsys, or against the OS filesystem when fsys is nil.
+func glob(fsys fs.FS, pattern string) ([]string, error) {
+	if fsys != nil {
+		return fs.Glob(fsys, pattern)
+	}
+
+	return filepath.Glob(pattern)
+}
+
+// readFile reads path from fsys, or from the OS filesystem when fsys is nil.
+func readFile(fsys fs.FS, path string) ([]byte, error) {
+	if fsys != nil {
+		return fs.ReadFile(fsys, path)
+	}
+
+	return os.ReadFile(path)
 }
@@ -0,0 +1,370 @@
+package routek
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// newTestRouter builds a router from a YAML route document, failing the test when it does not build.
+func newTestRouter(tb testing.TB, doc string, cfg Config) *router.Router {
+	tb.Helper()
+
+	rt, err := NewRouterFromBytes([]byte(doc), cfg)
+	if err != nil {
+		tb.Fatalf("NewRouterFromBytes: %v", err)
+	}
+
+	return rt
+}
+
+// serve sends a request through handler and returns the ctx holding the response. headers alternate
+// names and values.
+func serve(handler fasthttp.RequestHandler, method, uri string, headers ...string) *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	req.Header.SetMethod(method)
+	req.SetRequestURI(uri)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Init(&req, nil, nil)
+	handler(ctx)
+
+	return ctx
+}
+
+// decodeEnvelope decodes the JSON envelope of a response into a map.
+func decodeEnvelope(tb testing.TB, body []byte) map[string]any {
+	tb.Helper()
+
+	var envelope map[string]any
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		tb.Fatalf("decode envelope %q: %v", body, err)
+	}
+
+	return envelope
+}
+
+type pathHandlers struct{}
+
+func (pathHandlers) File(ctx *fasthttp.RequestCtx) (any, error) {
+	return ctx.UserValue("filepath"), nil
+}
+
+func (pathHandlers) App(ctx *fasthttp.RequestCtx) (any, error) {
+	return "app", nil
+}
+
+func TestCatchAllRoutes(t *testing.T) {
+	rt := newTestRouter(t, `
+web:
+  route:
+    - get: /static/*filepath
+      handler: File
+    - get: /*path
+      handler: App
+`, Config{Handlers: map[string]any{"web": pathHandlers{}}})
+
+	tests := []struct {
+		uri  string
+		data any
+	}{
+		{"/static/css/site.css", "css/site.css"},
+		{"/static/", ""},
+		{"/settings/profile", "app"},
+		{"/", "app"},
+	}
+	for _, tt := range tests {
+		ctx := serve(rt.Handler, fasthttp.MethodGet, tt.uri)
+		if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", tt.uri, status)
+			continue
+		}
+		if data := decodeEnvelope(t, ctx.Response.Body())["data"]; data != tt.data {
+			t.Errorf("GET %s: data %v, want %v", tt.uri, data, tt.data)
+		}
+	}
+}
+
+func TestConflictingCatchAllIsAnError(t *testing.T) {
+	docs := map[string]string{
+		"two catch-alls": `
+web:
+  route:
+    - get: /static/*filepath
+      handler: File
+    - get: /static/*other
+      handler: App
+`,
+		"catch-all and brace form": `
+web:
+  route:
+    - get: /static/*filepath
+      handler: File
+    - get: /static/{rest:*}
+      handler: App
+`,
+	}
+	for name, doc := range docs {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewRouterFromBytes([]byte(doc), Config{Handlers: map[string]any{"web": pathHandlers{}}})
+			if err == nil {
+				t.Fatal("NewRouterFromBytes succeeded, want a conflict error")
+			}
+			if !strings.HasPrefix(err.Error(), "routek: ") {
+				t.Errorf("error %q does not start with routek:", err)
+			}
+		})
+	}
+}
+
+type probeHandlers struct{}
+
+func (probeHandlers) Ping(ctx *fasthttp.RequestCtx) (any, error) {
+	return "pong", nil
+}
+
+func (probeHandlers) Delete(ctx *fasthttp.RequestCtx) error {
+	return nil
+}
+
+func TestAnchoredRouteBlocks(t *testing.T) {
+	tagged := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Set("X-Audited", "yes")
+			next(ctx)
+		}
+	}
+	rt := newTestRouter(t, `
+x-probes: &probes
+  - get: /ping
+    handler: Ping
+
+x-audited: &audited
+  middleware: [audit]
+
+users:
+  prefix: /users
+  route: *probes
+orders:
+  prefix: /orders
+  route:
+    - get: /ping
+      handler: Ping
+    - <<: *audited
+      delete: /{id}
+      handler: Delete
+`, Config{
+		Handlers:   map[string]any{"users": probeHandlers{}, "orders": probeHandlers{}},
+		Middleware: map[string]Middleware{"audit": tagged},
+	})
+
+	for _, uri := range []string{"/users/ping", "/orders/ping"} {
+		if status := serve(rt.Handler, fasthttp.MethodGet, uri).Response.StatusCode(); status != fasthttp.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", uri, status)
+		}
+	}
+
+	ctx := serve(rt.Handler, fasthttp.MethodDelete, "/orders/7")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusNoContent {
+		t.Errorf("DELETE /orders/7: status %d, want 204", status)
+	}
+	if got := string(ctx.Response.Header.Peek("X-Audited")); got != "yes" {
+		t.Errorf("DELETE /orders/7: merged middleware did not run, X-Audited = %q", got)
+	}
+	if got := string(serve(rt.Handler, fasthttp.MethodGet, "/orders/ping").Response.Header.Peek("X-Audited")); got != "" {
+		t.Errorf("GET /orders/ping: X-Audited = %q, want the merge limited to its route", got)
+	}
+}
+
+func TestExtensionKeysAreNotGroups(t *testing.T) {
+	_, routes, err := NewRouterWithRoutes(Config{
+		RouteFile: "routes.yaml",
+		Loader: func(string) ([]byte, error) {
+			return []byte("x-probes: &probes\n  - get: /ping\n    handler: Ping\nusers:\n  route: *probes\n"), nil
+		},
+		Handlers: map[string]any{"users": probeHandlers{}},
+	})
+	if err != nil {
+		t.Fatalf("NewRouterWithRoutes: %v", err)
+	}
+	if len(routes) != 1 || routes[0].Group != "users" {
+		t.Errorf("registered %+v, want only the users ping route", routes)
+	}
+}
+
+// orderMiddleware appends name to the X-Order header, recording the order middleware ran in.
+func orderMiddleware(name string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			ctx.Response.Header.Add("X-Order", name)
+			next(ctx)
+		}
+	}
+}
+
+func TestNestedGroups(t *testing.T) {
+	doc := `
+admin:
+  prefix: /admin
+  middleware: [auth]
+  groups:
+    users:
+      prefix: /users
+      middleware: [audit]
+      route:
+        - get: /ping
+          handler: Ping
+      groups:
+        roles:
+          prefix: /{id}/roles
+          route:
+            - get: /ping
+              handler: Ping
+    settings:
+      prefix: /settings
+      route:
+        - get: /ping
+          handler: Ping
+`
+	cfg := Config{
+		RouteFile: "routes.yaml",
+		Loader:    func(string) ([]byte, error) { return []byte(doc), nil },
+		// Only the top-level group has a target; subgroups inherit it.
+		Handlers: map[string]any{"admin": probeHandlers{}},
+		Middleware: map[string]Middleware{
+			"auth":  orderMiddleware("auth"),
+			"audit": orderMiddleware("audit"),
+		},
+	}
+	rt, routes, err := NewRouterWithRoutes(cfg)
+	if err != nil {
+		t.Fatalf("NewRouterWithRoutes: %v", err)
+	}
+
+	groups := make(map[string]string)
+	for _, route := range routes {
+		groups[route.Path] = route.Group
+	}
+	wantGroups := map[string]string{
+		"/admin/users/ping":            "admin.users",
+		"/admin/users/{id}/roles/ping": "admin.users.roles",
+		"/admin/settings/ping":         "admin.settings",
+	}
+	if len(groups) != len(wantGroups) {
+		t.Errorf("registered %v, want %v", groups, wantGroups)
+	}
+	for path, group := range wantGroups {
+		if groups[path] != group {
+			t.Errorf("%s registered in group %q, want %q", path, groups[path], group)
+		}
+	}
+
+	tests := []struct {
+		uri   string
+		order []string
+	}{
+		{"/admin/users/ping", []string{"auth", "audit"}},
+		{"/admin/users/7/roles/ping", []string{"auth", "audit"}},
+		{"/admin/settings/ping", []string{"auth"}},
+	}
+	for _, tt := range tests {
+		ctx := serve(rt.Handler, fasthttp.MethodGet, tt.uri)
+		if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", tt.uri, status)
+			continue
+		}
+		var order []string
+		ctx.Response.Header.VisitAll(func(key, value []byte) {
+			if string(key) == "X-Order" {
+				order = append(order, string(value))
+			}
+		})
+		if strings.Join(order, ",") != strings.Join(tt.order, ",") {
+			t.Errorf("GET %s: middleware ran as %v, want %v", tt.uri, order, tt.order)
+		}
+	}
+}
+
+func TestEmptyGroups(t *testing.T) {
+	doc := `
+users:
+  route:
+    - get: /ping
+      handler: Ping
+reports:
+  prefix: /reports
+`
+	handlers := map[string]any{"users": probeHandlers{}}
+
+	t.Run("warns", func(t *testing.T) {
+		var logs bytes.Buffer
+		rt := newTestRouter(t, doc, Config{Handlers: handlers, Logger: slog.New(slog.NewTextHandler(&logs, nil))})
+
+		if status := serve(rt.Handler, fasthttp.MethodGet, "/ping").Response.StatusCode(); status != fasthttp.StatusOK {
+			t.Errorf("GET /ping: status %d, want 200", status)
+		}
+		if out := logs.String(); !strings.Contains(out, "declares no routes") || !strings.Contains(out, "group=reports") {
+			t.Errorf("log %q does not warn about the reports group", out)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		_, err := NewRouterFromBytes([]byte(doc), Config{Handlers: handlers, StrictGroups: true})
+		if err == nil {
+			t.Fatal("NewRouterFromBytes succeeded, want an empty group error")
+		}
+		if want := `routek: group "reports" declares no routes`; !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q, want %q", err, want)
+		}
+	})
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	rt := newTestRouter(t, `
+admin:
+  prefix: /admin
+  middleware: [group, auth]
+  groups:
+    users:
+      prefix: /users
+      middleware: [nested, group]
+      route:
+        - get: /ping
+          handler: Ping
+          middleware: [route, nested, route]
+`, Config{
+		Handlers:              map[string]any{"admin": probeHandlers{}},
+		GlobalMiddleware:      []Middleware{orderMiddleware("global")},
+		GlobalMiddlewareNames: []string{"auth"},
+		Middleware: map[string]Middleware{
+			"auth":   orderMiddleware("auth"),
+			"group":  orderMiddleware("group"),
+			"nested": orderMiddleware("nested"),
+			"route":  orderMiddleware("route"),
+		},
+	})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/admin/users/ping")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	var order []string
+	ctx.Response.Header.VisitAll(func(key, value []byte) {
+		if string(key) == "X-Order" {
+			order = append(order, string(value))
+		}
+	})
+	// auth is named globally and by the group, group by both groups, nested by the subgroup and the
+	// route, and route twice by the route: each runs once, at its outermost position.
+	if want := "global,auth,group,nested,route"; strings.Join(order, ",") != want {
+		t.Errorf("middleware ran as %v, want %s", order, want)
+	}
+}
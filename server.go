@@ -0,0 +1,44 @@
+package routek
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Defaults applied by NewServer.
+const (
+	DefaultReadTimeout        = 10 * time.Second
+	DefaultWriteTimeout       = 10 * time.Second
+	DefaultIdleTimeout        = 60 * time.Second
+	DefaultMaxRequestBodySize = 4 << 20
+)
+
+// NewServer builds the router like NewRouter and returns a fasthttp.Server serving it with the Default*
+// timeouts and body size. When a route declares a larger max_body, MaxRequestBodySize is raised to it so
+// the route limit stays reachable. Override any field before calling ListenAndServe.
+func NewServer(cfg Config) (*fasthttp.Server, error) {
+	rt, routes, err := NewRouterWithRoutes(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBody := int64(DefaultMaxRequestBodySize)
+	for _, route := range routes {
+		if route.route == nil || route.route.MaxBody == "" {
+			continue
+		}
+		// The size was already validated while building the router.
+		if limit, err := parseSize(route.route.MaxBody); err == nil && limit > maxBody {
+			maxBody = limit
+		}
+	}
+
+	return &fasthttp.Server{
+		Handler:            rt.Handler,
+		ReadTimeout:        DefaultReadTimeout,
+		WriteTimeout:       DefaultWriteTimeout,
+		IdleTimeout:        DefaultIdleTimeout,
+		MaxRequestBodySize: int(maxBody),
+	}, nil
+}
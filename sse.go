@@ -0,0 +1,104 @@
+package routek
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Event is a server-sent event, sent by a streaming handler on its channel.
+type Event struct {
+	// ID sets the client's last event ID; empty omits it.
+	ID string
+	// Event names the event type; empty is the default "message" type.
+	Event string
+	// Data is the payload; each line is sent as its own data field.
+	Data string
+	// Retry asks the client to wait this long before reconnecting; zero omits it.
+	Retry time.Duration
+}
+
+// streamHandler serves a func(*fasthttp.RequestCtx, chan<- Event) handler as a text/event-stream
+// response, flushing every event the handler sends at once; the stream ends when it returns.
+//
+// fasthttp writes streamed bodies from its own goroutine and recycles ctx as soon as the client goes
+// away, so the handler is given a copy of the request with its user values instead. Context of that
+// copy is cancelled when the client goes away or the server shuts down; events sent afterwards are
+// discarded.
+func (b *binding) streamHandler(fn func(*fasthttp.RequestCtx, chan<- Event)) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		ctx.SetContentType("text/event-stream")
+		ctx.Response.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+		// Ask reverse proxies such as nginx not to buffer the stream.
+		ctx.Response.Header.Set("X-Accel-Buffering", "no")
+
+		stream := &fasthttp.RequestCtx{}
+		stream.Init(&ctx.Request, ctx.RemoteAddr(), nil)
+		ctx.VisitUserValuesAll(func(key, value any) {
+			stream.SetUserValue(key, value)
+		})
+
+		// The stream outlives the route's middleware, including a route timeout, so only the client and
+		// the server end it.
+		streamCtx, cancel := context.WithCancel(context.WithoutCancel(Context(ctx)))
+		stop := context.AfterFunc(serving(ctx), cancel)
+		stream.SetUserValue(contextKey, streamCtx)
+		path := string(ctx.Path())
+
+		ctx.SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer stop()
+
+			events := make(chan Event)
+			go func() {
+				defer close(events)
+				defer func() {
+					// The router's panic handling does not cover the stream; a panic would take down the server.
+					if recovered := recover(); recovered != nil && b.logger != nil {
+						b.logger.Error("routek: event stream handler panicked", "path", path, "panic", recovered)
+					}
+				}()
+
+				fn(stream, events)
+			}()
+
+			failed := false
+			for event := range events {
+				if failed {
+					continue
+				}
+				writeEvent(w, event)
+				if err := w.Flush(); err != nil {
+					failed = true
+					cancel()
+				}
+			}
+		})
+	}
+}
+
+// writeEvent writes event in the text/event-stream format.
+func writeEvent(w *bufio.Writer, event Event) {
+	if event.ID != "" {
+		w.WriteString("id: " + oneLine(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		w.WriteString("event: " + oneLine(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		w.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\n") {
+		w.WriteString("data: " + line + "\n")
+	}
+	w.WriteString("\n")
+}
+
+// oneLine keeps a field from ending early or starting a new one.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,167 @@
+package routek
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/fasthttp/router"
+	"github.com/valyala/fasthttp"
+)
+
+// StaticRoute serves the files under Dir on GET requests matching Path, declared under a group's
+// `static` key. Path ends in a catch-all segment naming the file, such as /assets/*filepath; one is
+// appended when it is missing.
+type StaticRoute struct {
+	Path string `yaml:"path" json:"path"`
+	// Dir is the directory served, resolved against Config.FS when set and the working directory otherwise.
+	Dir string `yaml:"dir" json:"dir"`
+	// Index names the files served for a directory request, in order. Defaults to index.html.
+	Index []string `yaml:"index" json:"index"`
+	// Browse lists the contents of directories without an index file instead of answering 403.
+	Browse bool `yaml:"browse" json:"browse"`
+}
+
+// defaultIndexNames are the index files tried for a directory when a static route names none.
+var defaultIndexNames = []string{"index.html"}
+
+// staticPath returns the static route's full path ending in a {name:*} catch-all, and the name.
+func staticPath(prefix, routePath string) (string, string) {
+	full := catchAll(joinPath(prefix, routePath))
+	i := strings.LastIndexByte(full, '/')
+	if segment := full[i+1:]; strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, ":*}") {
+		return full, segment[1 : len(segment)-3]
+	}
+
+	return strings.TrimRight(full, "/") + "/{filepath:*}", "filepath"
+}
+
+// staticHandler serves s.Dir for requests whose catch-all parameter param names the file. Missing files
+// are answered with a 404 through the responder.
+func staticHandler(s StaticRoute, param string, fsys fs.FS, responder Responder) (fasthttp.RequestHandler, error) {
+	if s.Dir == "" {
+		return nil, fmt.Errorf("static route %q does not declare a dir", s.Path)
+	}
+
+	handler := &fasthttp.FS{
+		Root:               s.Dir,
+		IndexNames:         s.Index,
+		GenerateIndexPages: s.Browse,
+		AcceptByteRange:    true,
+		PathRewrite: func(ctx *fasthttp.RequestCtx) []byte {
+			file, _ := ctx.UserValue(param).(string)
+			return []byte("/" + file)
+		},
+		PathNotFound: func(ctx *fasthttp.RequestCtx) {
+			responder.Error(ctx, fasthttp.StatusNotFound, CodeNotFound, "Not Found", nil)
+		},
+	}
+	if handler.IndexNames == nil {
+		handler.IndexNames = defaultIndexNames
+	}
+
+	var info fs.FileInfo
+	var err error
+	if fsys != nil {
+		dir := path.Clean(s.Dir)
+		if info, err = fs.Stat(fsys, dir); err == nil {
+			handler.FS, err = fs.Sub(fsys, dir)
+			handler.Root, handler.AllowEmptyRoot = "", true
+		}
+	} else {
+		info, err = os.Stat(s.Dir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("static dir %q: %w", s.Dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("static dir %q is not a directory", s.Dir)
+	}
+
+	return handler.NewRequestHandler(), nil
+}
+
+// registerStatic registers the static routes of a flattened group on rt behind the group's middleware, the
+// global middleware and the panic recovery, tracing and observers of every route, recording problems in p.
+// It reports true when the build should stop.
+func registerStatic(cfg Config, group string, routes Group, rt *router.Router, responder Responder, global []Middleware, registered map[string]string, p *problems) ([]RegisteredRoute, bool) {
+	prefix, err := expandEnv(routes.Prefix)
+	if err != nil {
+		return nil, p.add(fmt.Errorf("routek: group %q prefix: %w", group, err))
+	}
+	prefix = joinPath(basePrefix(cfg), prefix)
+
+	middleware, err := resolveMiddleware(cfg.Middleware, uniqueNames(routes.Middleware, cfg.GlobalMiddlewareNames))
+	if err != nil {
+		return nil, p.add(fmt.Errorf("routek: %s static: %w", group, err))
+	}
+
+	var list []RegisteredRoute
+	for _, s := range routes.Static {
+		if s.Path == "" {
+			if p.add(fmt.Errorf("routek: %s static: static route does not declare a path", group)) {
+				return nil, true
+			}
+			continue
+		}
+
+		routePath, err := expandEnv(s.Path)
+		if err != nil {
+			if p.add(fmt.Errorf("routek: %s static: %w", group, err)) {
+				return nil, true
+			}
+			continue
+		}
+		full, param := staticPath(prefix, routePath)
+		if err := checkPathDepth(cfg, full); err != nil {
+			if p.add(fmt.Errorf("routek: %s static: %w", group, err)) {
+				return nil, true
+			}
+			continue
+		}
+
+		handler, err := staticHandler(s, param, cfg.FS, responder)
+		if err != nil {
+			if p.add(fmt.Errorf("routek: %s static: %w", group, err)) {
+				return nil, true
+			}
+			continue
+		}
+
+		key := fasthttp.MethodGet + " " + full
+		if err := checkRouteCount(cfg, registered, key); err != nil {
+			p.add(fmt.Errorf("routek: %s static: %w", group, err))
+			return nil, true
+		}
+		if owner, dup := registered[key]; dup {
+			if owner == group {
+				err = fmt.Errorf("routek: duplicate route %s defined twice in group %q", key, group)
+			} else {
+				err = fmt.Errorf("routek: duplicate route %s defined in groups %q and %q", key, owner, group)
+			}
+			if p.add(err) {
+				return nil, true
+			}
+			continue
+		}
+		registered[key] = group
+
+		handler = chain(chain(handler, middleware...), global...)
+		if cfg.RecoverPanics {
+			handler = recoverMiddleware(group, cfg.PanicResponse, cfg.PanicHook, responder)(handler)
+		}
+		handler = withResponder(responder, cfg.Logger)(handler)
+		handler = observe(cfg, handler, group, "", fasthttp.MethodGet, full)
+		if err := register(rt, fasthttp.MethodGet, full, handler); err != nil {
+			if p.add(fmt.Errorf("routek: %s static: %w", group, err)) {
+				return nil, true
+			}
+			continue
+		}
+		list = append(list, RegisteredRoute{Method: fasthttp.MethodGet, Path: full, Group: group})
+	}
+
+	return list, false
+}
@@ -0,0 +1,41 @@
+package routek
+
+import "github.com/fasthttp/router"
+
+// RouteSummary counts the registered routes, e.g. for a sanity check logged at startup. Each method and
+// path pair is one route.
+type RouteSummary struct {
+	Total int
+	// Groups counts routes per group; built-in routes such as the health check have no group and are
+	// only included in Total.
+	Groups map[string]int
+	// Methods counts routes per HTTP method.
+	Methods map[string]int
+}
+
+// NewRouterWithSummary builds the router like NewRouter and also returns a summary of its routes.
+func NewRouterWithSummary(cfg Config) (*router.Router, RouteSummary, error) {
+	rt, routes, err := NewRouterWithRoutes(cfg)
+	if err != nil {
+		return nil, RouteSummary{}, err
+	}
+
+	return rt, Summarize(routes), nil
+}
+
+// Summarize counts routes by group and method.
+func Summarize(routes []RegisteredRoute) RouteSummary {
+	summary := RouteSummary{
+		Total:   len(routes),
+		Groups:  make(map[string]int),
+		Methods: make(map[string]int),
+	}
+	for _, route := range routes {
+		if route.Group != "" {
+			summary.Groups[route.Group]++
+		}
+		summary.Methods[route.Method]++
+	}
+
+	return summary
+}
@@ -0,0 +1,59 @@
+package routek
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tenantPath converts the :name segments of a Config.TenantPrefix such as /t/:tenant to the router's
+// {name} form, with a leading slash and no trailing one.
+func tenantPath(prefix string) string {
+	segments := strings.Split(prefix, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+
+	return joinPath(strings.Join(segments, "/"), "")
+}
+
+// checkTenantPrefix rejects tenant prefixes whose parameters are not plain path segments.
+func checkTenantPrefix(prefix string) error {
+	path := tenantPath(prefix)
+	if strings.Contains(path, "?}") || strings.Contains(path, ":*}") || strings.Contains(path, "*") {
+		return fmt.Errorf("routek: Config.TenantPrefix %q must not contain optional or catch-all parameters", prefix)
+	}
+	for name := range pathParams(path) {
+		if name == "" {
+			return fmt.Errorf("routek: Config.TenantPrefix %q has an unnamed parameter", prefix)
+		}
+	}
+
+	return nil
+}
+
+// basePrefix returns what precedes every group prefix: Config.MountPath, then Config.TenantPrefix.
+func basePrefix(cfg Config) string {
+	if cfg.TenantPrefix == "" {
+		return cfg.MountPath
+	}
+
+	return joinPath(cfg.MountPath, tenantPath(cfg.TenantPrefix))
+}
+
+// tenantCollision returns a parameter of path, relative to the tenant prefix, that the prefix already declares.
+func tenantCollision(cfg Config, path string) (string, bool) {
+	if cfg.TenantPrefix == "" {
+		return "", false
+	}
+
+	tenant := pathParams(tenantPath(cfg.TenantPrefix))
+	for name := range pathParams(path) {
+		if tenant[name] {
+			return name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,136 @@
+package routek
+
+import (
+	"context"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// contextKey is the user value under which a route's context.Context is stored.
+const contextKey = "routek.context"
+
+// Context returns the context.Context for the request. For routes with a timeout it is cancelled when
+// the timeout elapses, and with a Tracer it carries the route's span. Otherwise it is the
+// *fasthttp.RequestCtx itself, which is done on server shutdown; outside a server the ctx must be set
+// up with RequestCtx.Init for that to work.
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(contextKey).(context.Context); ok {
+		return c
+	}
+
+	return ctx
+}
+
+// timeoutMiddleware bounds the handler to timeout, answering 504 through the responder when it is exceeded.
+//
+// fasthttp handlers cannot be interrupted, so the handler runs in its own goroutine and keeps running after
+// the timeout; it should watch Context(ctx).Done() and return promptly. It is given a copy of ctx, which is
+// copied back when it returns in time and abandoned otherwise, so the outer middleware never reads state a
+// late handler is still writing. Once the timeout fires the 504 is sent via RequestCtx.TimeoutErrorWithResponse.
+func timeoutMiddleware(timeout time.Duration, responder Responder) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			reqCtx, cancel := context.WithTimeout(Context(ctx), timeout)
+			defer cancel()
+			requestID := RequestID(ctx)
+
+			inner := detach(ctx)
+			inner.SetUserValue(contextKey, reqCtx)
+
+			done := make(chan struct{})
+			var recovered any
+			var stack []byte
+			go func() {
+				defer close(done)
+				defer func() {
+					if recovered = recover(); recovered != nil {
+						stack = debug.Stack()
+					}
+				}()
+
+				next(inner)
+			}()
+
+			select {
+			case <-done:
+				attach(ctx, inner)
+				// Re-panic on the serving goroutine with the original value, so the route's panic handling and
+				// any recovering middleware still apply. The stack of the goroutine the handler panicked on is
+				// left for recoverPanic.
+				if recovered != nil {
+					ctx.SetUserValue(panicStackKey, stack)
+					panic(recovered)
+				}
+			case <-reqCtx.Done():
+				// Render the error into a scratch context: ctx.Response is left as it was. It starts with the
+				// headers outer middleware set, such as CORS and route headers, which the handler's copy
+				// cannot be writing.
+				var scratch fasthttp.RequestCtx
+				ctx.Request.Header.CopyTo(&scratch.Request.Header)
+				ctx.Response.Header.CopyTo(&scratch.Response.Header)
+				if requestID != "" {
+					scratch.SetUserValue(requestIDKey, requestID)
+					scratch.Response.Header.Set(RequestIDHeader, requestID)
+				}
+				responder.Error(&scratch, fasthttp.StatusGatewayTimeout, CodeGatewayTimeout, "gateway timeout", reqCtx.Err())
+				ctx.TimeoutErrorWithResponse(&scratch.Response)
+			}
+		}
+	}
+}
+
+// servingKey is the user value under which a detached copy keeps the ctx being served.
+const servingKey = "routek.serving"
+
+// serving returns the ctx the server is serving, of which ctx may be a detached copy. Unlike a copy, it
+// is done when the server shuts down.
+func serving(ctx *fasthttp.RequestCtx) *fasthttp.RequestCtx {
+	if s, ok := ctx.UserValue(servingKey).(*fasthttp.RequestCtx); ok {
+		return s
+	}
+
+	return ctx
+}
+
+// detach returns a copy of ctx on the same connection, with the request, the response written so far and
+// the user values.
+func detach(ctx *fasthttp.RequestCtx) *fasthttp.RequestCtx {
+	inner := &fasthttp.RequestCtx{}
+	inner.Init2(ctx.Conn(), log.Default(), false)
+	inner.SetRemoteAddr(ctx.RemoteAddr())
+	ctx.Request.CopyTo(&inner.Request)
+	if ctx.Request.IsBodyStream() {
+		inner.Request.SetBodyStream(ctx.Request.BodyStream(), ctx.Request.Header.ContentLength())
+	}
+	ctx.Response.CopyTo(&inner.Response)
+	ctx.VisitUserValuesAll(func(key, value any) {
+		inner.SetUserValue(key, value)
+	})
+	inner.SetUserValue(servingKey, serving(ctx))
+
+	return inner
+}
+
+// attach copies the response and user values of a detached copy back into ctx.
+func attach(ctx, inner *fasthttp.RequestCtx) {
+	inner.Response.CopyTo(&ctx.Response)
+	if inner.Response.IsBodyStream() {
+		ctx.Response.SetBodyStream(inner.Response.BodyStream(), inner.Response.Header.ContentLength())
+	}
+	inner.VisitUserValuesAll(func(key, value any) {
+		ctx.SetUserValue(key, value)
+	})
+}
+
+// responseStatus returns the status of the response sent for ctx. Once a route timeout has fired that is
+// the timeout response's, and ctx.Response is not read.
+func responseStatus(ctx *fasthttp.RequestCtx) int {
+	if resp := ctx.LastTimeoutErrorResponse(); resp != nil {
+		return resp.StatusCode()
+	}
+
+	return ctx.Response.StatusCode()
+}
@@ -0,0 +1,131 @@
+package routek
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type slowHandlers struct{}
+
+func (slowHandlers) Wait(ctx *fasthttp.RequestCtx) (any, error) {
+	<-Context(ctx).Done()
+	return nil, Context(ctx).Err()
+}
+
+func (slowHandlers) Boom(ctx *fasthttp.RequestCtx) (any, error) {
+	panic("boom")
+}
+
+const timedRoutes = `
+jobs:
+  route:
+    - get: /boom
+      handler: Boom
+      timeout: 1s
+`
+
+func TestTimedRoutePanicWithoutRecover(t *testing.T) {
+	rt := newTestRouter(t, timedRoutes, Config{Handlers: map[string]any{"jobs": slowHandlers{}}})
+
+	defer func() {
+		if recovered := recover(); recovered != "boom" {
+			t.Errorf("route panicked with %#v, want the handler's panic value", recovered)
+		}
+	}()
+	serve(rt.Handler, fasthttp.MethodGet, "/boom")
+}
+
+func TestTimedRoutePanicInRecoveringMiddleware(t *testing.T) {
+	var seen any
+	var stack []byte
+	catch := func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			defer func() {
+				if seen = recover(); seen != nil {
+					stack = PanicStack(ctx)
+					ctx.SetStatusCode(fasthttp.StatusTeapot)
+				}
+			}()
+			next(ctx)
+		}
+	}
+	rt := newTestRouter(t, timedRoutes, Config{
+		Handlers:         map[string]any{"jobs": slowHandlers{}},
+		GlobalMiddleware: []Middleware{catch},
+	})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/boom")
+	if seen != "boom" {
+		t.Fatalf("recovering middleware got %#v, want the handler's panic value", seen)
+	}
+	if !strings.Contains(string(stack), "slowHandlers.Boom") {
+		t.Errorf("PanicStack does not show the handler's goroutine:\n%s", stack)
+	}
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusTeapot {
+		t.Errorf("status %d, want the middleware's 418", status)
+	}
+}
+
+func TestTimedRoutePanicWithRecover(t *testing.T) {
+	var seen any
+	var stack []byte
+	rt := newTestRouter(t, timedRoutes, Config{
+		Handlers:      map[string]any{"jobs": slowHandlers{}},
+		RecoverPanics: true,
+		PanicHook: func(ctx *fasthttp.RequestCtx, recovered any) {
+			seen, stack = recovered, PanicStack(ctx)
+		},
+	})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/boom")
+	if status := ctx.Response.StatusCode(); status != fasthttp.StatusInternalServerError {
+		t.Errorf("status %d, want 500", status)
+	}
+	if code := decodeEnvelope(t, ctx.Response.Body())["code"]; code != string(CodeInternalError) {
+		t.Errorf("code %v, want %s", code, CodeInternalError)
+	}
+	if seen != "boom" {
+		t.Errorf("PanicHook got %#v, want the handler's panic value", seen)
+	}
+	if !strings.Contains(string(stack), "slowHandlers.Boom") {
+		t.Errorf("PanicStack does not show the handler's goroutine:\n%s", stack)
+	}
+}
+
+func TestTimeoutKeepsOuterHeaders(t *testing.T) {
+	rt := newTestRouter(t, `
+jobs:
+  route:
+    - get: /wait
+      handler: Wait
+      timeout: 10ms
+      headers: {X-Frame-Options: DENY}
+`, Config{
+		Handlers:         map[string]any{"jobs": slowHandlers{}},
+		GlobalMiddleware: []Middleware{CORS(CORSOptions{AllowedOrigins: []string{"https://app.example.com"}})},
+	})
+
+	ctx := serve(rt.Handler, fasthttp.MethodGet, "/wait", "Origin", "https://app.example.com")
+	resp := ctx.LastTimeoutErrorResponse()
+	if resp == nil {
+		t.Fatal("route did not time out")
+	}
+	if status := resp.StatusCode(); status != fasthttp.StatusGatewayTimeout {
+		t.Errorf("status %d, want 504", status)
+	}
+	want := map[string]string{
+		"X-Frame-Options":             "DENY",
+		"Access-Control-Allow-Origin": "https://app.example.com",
+		"Content-Type":                "application/json",
+	}
+	for name, value := range want {
+		if got := string(resp.Header.Peek(name)); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if code := decodeEnvelope(t, resp.Body())["code"]; code != string(CodeGatewayTimeout) {
+		t.Errorf("code %v, want %s", code, CodeGatewayTimeout)
+	}
+}
@@ -0,0 +1,67 @@
+package routek
+
+import (
+	"context"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware runs each request inside a span named after the route's group and handler.
+// The incoming trace context is extracted with the global propagator, the span context is made
+// available through Context(ctx), and the span ends after the response has been written. A route timeout
+// is recorded as context.DeadlineExceeded with the 504 it sent.
+func tracingMiddleware(tracer trace.Tracer, name, method, route string) Middleware {
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			parent := otel.GetTextMapPropagator().Extract(Context(ctx), requestHeaderCarrier{&ctx.Request.Header})
+			spanCtx, span := tracer.Start(parent, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", method),
+					attribute.String("http.route", route),
+				),
+			)
+			defer span.End()
+
+			ctx.SetUserValue(contextKey, spanCtx)
+			next(ctx)
+
+			status := responseStatus(ctx)
+			span.SetAttributes(attribute.Int("http.response.status_code", status))
+			if ctx.LastTimeoutErrorResponse() != nil {
+				span.RecordError(context.DeadlineExceeded)
+			} else if err := handlerError(ctx); err != nil {
+				span.RecordError(err)
+			}
+			if status >= fasthttp.StatusInternalServerError {
+				span.SetStatus(codes.Error, fasthttp.StatusMessage(status))
+			}
+		}
+	}
+}
+
+// requestHeaderCarrier adapts fasthttp request headers to propagation.TextMapCarrier.
+type requestHeaderCarrier struct {
+	header *fasthttp.RequestHeader
+}
+
+func (c requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+func (c requestHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+func (c requestHeaderCarrier) Keys() []string {
+	var keys []string
+	c.header.VisitAll(func(key, _ []byte) {
+		keys = append(keys, string(key))
+	})
+
+	return keys
+}
@@ -0,0 +1,238 @@
+package routek
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator checks a decoded request struct before the handler runs. v is a pointer to the struct.
+// Returning FieldErrors answers the request with 422 through the responder's ValidationError; any
+// other error is answered like a handler error.
+type Validator interface {
+	Validate(v any) error
+}
+
+// typeChecker is implemented by validators that can reject a request struct type when the router is built.
+type typeChecker interface {
+	checkType(t reflect.Type) error
+}
+
+// NewValidator returns a Validator reading `validate` struct tags, such as `validate:"required,email"`.
+// The rules are required, email, url, min=N, max=N, len=N and oneof=a b c; min, max and len bound
+// numbers by value and strings, slices and maps by length. Fields are named by their json tag, and
+// nested structs are checked with dotted names. Unknown rules fail NewRouter.
+func NewValidator() Validator {
+	return tagValidator{}
+}
+
+type tagValidator struct{}
+
+// validationRule is one parsed rule of a validate tag.
+type validationRule struct {
+	name string
+	arg  string
+}
+
+func (tagValidator) Validate(v any) error {
+	fields := make(FieldErrors)
+	validateStruct(reflect.Indirect(reflect.ValueOf(v)), "", fields)
+	if len(fields) > 0 {
+		return fields
+	}
+
+	return nil
+}
+
+func (tagValidator) checkType(t reflect.Type) error {
+	return checkRuleTags(t, make(map[reflect.Type]bool))
+}
+
+// checkRuleTags parses the validate tags of t and the structs nested in it; seen stops recursive types.
+func checkRuleTags(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return nil
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		if _, err := parseRules(sf.Tag.Get("validate")); err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		if err := checkRuleTags(sf.Type, seen); err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// parseRules parses a validate tag, rejecting unknown rules and malformed arguments.
+func parseRules(tag string) ([]validationRule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	var rules []validationRule
+	for _, part := range strings.Split(tag, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "required", "email", "url":
+			if arg != "" {
+				return nil, fmt.Errorf("validate rule %q takes no argument", name)
+			}
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(arg, 64); err != nil {
+				return nil, fmt.Errorf("validate rule %q needs a number, got %q", name, arg)
+			}
+		case "oneof":
+			if strings.TrimSpace(arg) == "" {
+				return nil, fmt.Errorf("validate rule %q needs values", name)
+			}
+		default:
+			return nil, fmt.Errorf("unknown validate rule %q", name)
+		}
+		rules = append(rules, validationRule{name: name, arg: arg})
+	}
+
+	return rules, nil
+}
+
+// validateStruct records the invalid fields of the struct value v under prefix.
+func validateStruct(v reflect.Value, prefix string, fields FieldErrors) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name := prefix + jsonName(sf)
+		field := v.Field(i)
+		// Checked by checkType when the router was built.
+		rules, _ := parseRules(sf.Tag.Get("validate"))
+		if message, ok := checkRules(field, rules); !ok {
+			fields[name] = message
+			continue
+		}
+
+		for field.Kind() == reflect.Pointer && !field.IsNil() {
+			field = field.Elem()
+		}
+		validateStruct(field, name+".", fields)
+	}
+}
+
+// jsonName returns the JSON name of a struct field.
+func jsonName(sf reflect.StructField) string {
+	if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+
+	return sf.Name
+}
+
+// checkRules applies rules to field in order, returning the message of the first one it breaks.
+func checkRules(field reflect.Value, rules []validationRule) (string, bool) {
+	for _, rule := range rules {
+		if rule.name == "required" {
+			if field.IsZero() {
+				return "is required", false
+			}
+			continue
+		}
+
+		// Other rules only apply to values that are present.
+		v := field
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return "", true
+			}
+			v = v.Elem()
+		}
+		if v.IsZero() && v.Kind() == reflect.String {
+			continue
+		}
+
+		if message, ok := checkRule(v, rule); !ok {
+			return message, false
+		}
+	}
+
+	return "", true
+}
+
+// checkRule applies a rule other than required to a non-pointer value.
+func checkRule(v reflect.Value, rule validationRule) (string, bool) {
+	switch rule.name {
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if v.Kind() != reflect.String || err != nil || addr.Address != v.String() {
+			return "must be a valid email address", false
+		}
+	case "url":
+		u, err := url.ParseRequestURI(v.String())
+		if v.Kind() != reflect.String || err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a valid URL", false
+		}
+	case "min", "max", "len":
+		bound, _ := strconv.ParseFloat(rule.arg, 64)
+		n, unit, ok := measure(v)
+		if !ok {
+			return "", true
+		}
+		switch {
+		case rule.name == "min" && n < bound:
+			return "must be at least " + rule.arg + unit, false
+		case rule.name == "max" && n > bound:
+			return "must be at most " + rule.arg + unit, false
+		case rule.name == "len" && n != bound:
+			return "must be exactly " + rule.arg + unit, false
+		}
+	case "oneof":
+		options := strings.Fields(rule.arg)
+		value := fmt.Sprint(v.Interface())
+		for _, option := range options {
+			if value == option {
+				return "", true
+			}
+		}
+		return "must be one of " + strings.Join(options, ", "), false
+	}
+
+	return "", true
+}
+
+// measure returns what min, max and len compare: a number's value, or the length of a string, slice or
+// map with the unit to name in messages.
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	default:
+		return 0, "", false
+	}
+}
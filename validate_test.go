@@ -0,0 +1,177 @@
+package routek
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type signup struct {
+	Name    string            `json:"name" validate:"required,min=2,max=20"`
+	Email   string            `json:"email" validate:"required,email"`
+	Website string            `json:"website" validate:"url"`
+	Age     *int              `json:"age" validate:"min=18"`
+	Plan    string            `json:"plan" validate:"oneof=free pro"`
+	Code    string            `json:"code" validate:"len=4"`
+	Tags    []string          `json:"tags" validate:"max=2"`
+	Labels  map[string]string `json:"labels" validate:"max=1"`
+	Address *address          `json:"address"`
+}
+
+func TestValidator(t *testing.T) {
+	young, adult := 16, 30
+	tests := []struct {
+		name   string
+		value  signup
+		fields FieldErrors
+	}{
+		{
+			name:  "valid",
+			value: signup{Name: "Ada", Email: "ada@example.com", Website: "https://example.com", Age: &adult, Plan: "pro", Code: "AB12"},
+		},
+		{
+			name:  "absent optional fields",
+			value: signup{Name: "Ada", Email: "ada@example.com"},
+		},
+		{
+			name:   "required",
+			value:  signup{},
+			fields: FieldErrors{"name": "is required", "email": "is required"},
+		},
+		{
+			name:  "formats",
+			value: signup{Name: "Ada", Email: "Ada <ada@example.com>", Website: "example.com"},
+			fields: FieldErrors{
+				"email":   "must be a valid email address",
+				"website": "must be a valid URL",
+			},
+		},
+		{
+			name: "ranges",
+			value: signup{
+				Name:   "A",
+				Email:  "ada@example.com",
+				Age:    &young,
+				Code:   "ABC",
+				Tags:   []string{"a", "b", "c"},
+				Labels: map[string]string{"a": "1", "b": "2"},
+			},
+			fields: FieldErrors{
+				"name":   "must be at least 2 characters",
+				"age":    "must be at least 18",
+				"code":   "must be exactly 4 characters",
+				"tags":   "must be at most 2 items",
+				"labels": "must be at most 1 items",
+			},
+		},
+		{
+			name:   "oneof",
+			value:  signup{Name: "Ada", Email: "ada@example.com", Plan: "gold"},
+			fields: FieldErrors{"plan": "must be one of free, pro"},
+		},
+		{
+			name:   "nested",
+			value:  signup{Name: "Ada", Email: "ada@example.com", Address: &address{}},
+			fields: FieldErrors{"address.city": "is required"},
+		},
+	}
+
+	validator := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(&tt.value)
+			if tt.fields == nil {
+				if err != nil {
+					t.Fatalf("Validate: %v, want no error", err)
+				}
+				return
+			}
+			fields, ok := err.(FieldErrors)
+			if !ok {
+				t.Fatalf("Validate returned %v, want FieldErrors", err)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+type signupHandlers struct{}
+
+func (signupHandlers) Create(ctx *fasthttp.RequestCtx, req *signup) (any, error) {
+	return req.Name, nil
+}
+
+func TestValidatorRoute(t *testing.T) {
+	cfg := Config{
+		RouteFile: "routes.yaml",
+		Loader: func(string) ([]byte, error) {
+			return []byte("signups:\n  route:\n    - post: /signups\n      handler: Create\n"), nil
+		},
+		Handlers:  map[string]any{"signups": signupHandlers{}},
+		Validator: NewValidator(),
+	}
+
+	resp, err := TestInvoke(cfg, fasthttp.MethodPost, "/signups", []byte(`{"name":"Ada","email":"nope"}`))
+	if err != nil {
+		t.Fatalf("TestInvoke: %v", err)
+	}
+	if status := resp.StatusCode(); status != fasthttp.StatusUnprocessableEntity {
+		t.Fatalf("status %d, want 422", status)
+	}
+	envelope := decodeEnvelope(t, resp.Body())
+	if envelope["code"] != string(CodeValidationFailed) {
+		t.Errorf("code %v, want %s", envelope["code"], CodeValidationFailed)
+	}
+	data, _ := envelope["data"].(map[string]any)
+	fields, _ := data["fields"].(map[string]any)
+	if len(fields) != 1 || fields["email"] != "must be a valid email address" {
+		t.Errorf("fields %v, want only the email error", data["fields"])
+	}
+
+	resp, err = TestInvoke(cfg, fasthttp.MethodPost, "/signups", []byte(`{"name":"Ada","email":"ada@example.com"}`))
+	if err != nil {
+		t.Fatalf("TestInvoke: %v", err)
+	}
+	if status := resp.StatusCode(); status != fasthttp.StatusOK {
+		t.Errorf("valid request: status %d, want 200", status)
+	}
+}
+
+type badRuleRequest struct {
+	Name string `json:"name" validate:"required,nonblank"`
+}
+
+type badRuleHandlers struct{}
+
+func (badRuleHandlers) Create(ctx *fasthttp.RequestCtx, req *badRuleRequest) (any, error) {
+	return nil, nil
+}
+
+func TestValidatorRejectsUnknownRules(t *testing.T) {
+	_, err := NewRouterFromBytes([]byte("signups:\n  route:\n    - post: /signups\n      handler: Create\n"), Config{
+		Handlers:  map[string]any{"signups": badRuleHandlers{}},
+		Validator: NewValidator(),
+	})
+	if err == nil {
+		t.Fatal("NewRouterFromBytes succeeded, want an unknown rule error")
+	}
+	if !strings.Contains(err.Error(), `unknown validate rule "nonblank"`) {
+		t.Errorf("error %q does not name the unknown rule", err)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	for _, tag := range []string{"required=yes", "min", "max=ten", "oneof=", "between=1 5"} {
+		if _, err := parseRules(tag); err == nil {
+			t.Errorf("parseRules(%q) succeeded, want an error", tag)
+		}
+	}
+}
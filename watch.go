@@ -0,0 +1,132 @@
+package routek
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+
+	"github.com/fasthttp/router"
+	"github.com/fsnotify/fsnotify"
+)
+
+// WatchRouter builds a router like NewRouter and rebuilds it whenever a route file changes on disk.
+//
+// The returned router has no routes of its own; every request is delegated to the most recently built
+// route table, which is swapped atomically so in-flight requests finish on the table they started with.
+// A reload that fails to read, parse or bind is logged, through Config.Logger when set, and the previous
+// table keeps serving.
+// The returned close func stops the watcher. WatchRouter only supports OS files, not Config.FS or Config.Loader.
+func WatchRouter(cfg Config) (*router.Router, func() error, error) {
+	if cfg.FS != nil {
+		return nil, nil, errors.New("routek: WatchRouter does not support Config.FS")
+	}
+	if cfg.Loader != nil {
+		return nil, nil, errors.New("routek: WatchRouter does not support Config.Loader")
+	}
+
+	files, err := routeFiles(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(cfg.RouteFiles) == 0 {
+		// Pin the resolved file so reloads never switch to a different candidate.
+		cfg.RouteFile = files[0]
+	}
+
+	reloadable, err := NewReloadableRouter(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, nil, fmt.Errorf("routek: watch route files: %w", err)
+	}
+
+	// Watch directories rather than files: editors commonly replace files via rename,
+	// which drops a watch held on the file itself.
+	watched := make(map[string]bool)
+	for _, file := range files {
+		watched[filepath.Clean(file)] = true
+		dir := filepath.Dir(file)
+		if err := watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, nil, fmt.Errorf("routek: watch %s: %w", dir, err)
+		}
+	}
+
+	// isRouteFile reports whether name is a loaded route file or a new file matching a RouteFiles pattern.
+	isRouteFile := func(name string) bool {
+		if watched[filepath.Clean(name)] {
+			return true
+		}
+
+		for _, pattern := range cfg.RouteFiles {
+			if ok, _ := filepath.Match(pattern, name); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	rt := router.New()
+	rt.RedirectTrailingSlash = false
+	rt.RedirectFixedPath = false
+	rt.HandleMethodNotAllowed = false
+	rt.HandleOPTIONS = false
+	rt.NotFound = reloadable.Handler
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if !isRouteFile(event.Name) || !event.Has(fsnotify.Write|fsnotify.Create) {
+					continue
+				}
+
+				if err := reloadable.Reload(cfg); err != nil {
+					if cfg.Logger != nil {
+						cfg.Logger.Error("routek: reload failed, keeping previous routes", "error", err)
+					} else {
+						log.Printf("routek: reload failed, keeping previous routes: %v", err)
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				if cfg.Logger != nil {
+					cfg.Logger.Error("routek: watch route files", "error", err)
+				} else {
+					log.Printf("routek: watch route files: %v", err)
+				}
+			}
+		}
+	}()
+
+	var closeOnce sync.Once
+	var closeErr error
+	stop := func() error {
+		closeOnce.Do(func() {
+			closeErr = watcher.Close()
+			wg.Wait()
+		})
+
+		return closeErr
+	}
+
+	return rt, stop, nil
+}